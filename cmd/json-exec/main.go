@@ -5,14 +5,19 @@ import (
 	"os"
 
 	"go.sophtrust.dev/json-exec/internal/cli"
+	"go.sophtrust.dev/json-exec/internal/cli/commands/run"
 	"go.sophtrust.dev/pkg/zerolog/v2"
 	"go.sophtrust.dev/pkg/zerolog/v2/log"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == run.HelperArg {
+		run.RunHelper(os.Args[2:])
+	}
+
 	zerolog.TimeFieldFormat = "2006-01-02T15:04:05.000Z07:00"
 	stdoutLevelWriter := zerolog.NewFilteredLevelWriter([]zerolog.Level{
-		zerolog.DebugLevel, zerolog.InfoLevel, zerolog.WarnLevel,
+		zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel, zerolog.WarnLevel,
 	}, os.Stdout)
 	stderrLevelWriter := zerolog.NewFilteredLevelWriter([]zerolog.Level{
 		zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel,
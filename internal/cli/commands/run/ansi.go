@@ -0,0 +1,48 @@
+package run
+
+import (
+	"regexp"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// _ansiEscape matches ANSI escape sequences: control sequences (eg: colors and cursor movement), operating system
+// commands (eg: window titles and hyperlinks) and other two-character escape sequences.
+var _ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]*[0-~]`)
+
+// cleanOutput handles ANSI escape sequences and carriage returns in the output according to the given mode.
+func cleanOutput(s string, mode string) string {
+	switch mode {
+	case config.ANSIStrip:
+		return stripANSI(s)
+	case config.ANSICollapse:
+		return collapseCarriageReturns(stripANSI(s))
+	}
+	return s
+}
+
+// collapseCarriageReturns keeps only the text after the last carriage return in each line, which is what would have
+// been left on the screen after progress updates overwrite the line.
+func collapseCarriageReturns(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripANSI removes ANSI escape sequences from the string.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return _ansiEscape.ReplaceAllString(s, "")
+}
@@ -0,0 +1,65 @@
+package run
+
+import (
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestStripANSI checks that escape sequences are removed while the rest of the text is kept.
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "plain text", want: "plain text"},
+		{s: "\x1b[1;31merror\x1b[0m: failed", want: "error: failed"},
+		{s: "\x1b[2K\x1b[1Gprogress", want: "progress"},
+		{s: "\x1b]0;window title\x07prompt", want: "prompt"},
+		{s: "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", want: "link"},
+		{s: "\x1b(Bcharset\x1b7saved", want: "charsetsaved"},
+		{s: "50%\r\x1b[32m100%\x1b[m", want: "50%\r100%"},
+	}
+	for _, tt := range tests {
+		if got := stripANSI(tt.s); got != tt.want {
+			t.Errorf("stripANSI(%q) = %q, expected %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestCollapseCarriageReturns checks that only the text after the last carriage return in each line is kept.
+func TestCollapseCarriageReturns(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "no returns\nhere", want: "no returns\nhere"},
+		{s: "10%\r50%\r100%", want: "100%"},
+		{s: "a\r\nb\r\n", want: "a\nb\n"},
+		{s: "first\r1\nsecond\r2\r\n", want: "1\n2\n"},
+		{s: "trailing\r", want: "trailing"},
+	}
+	for _, tt := range tests {
+		if got := collapseCarriageReturns(tt.s); got != tt.want {
+			t.Errorf("collapseCarriageReturns(%q) = %q, expected %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestCleanOutput checks that the output is handled according to the ANSI mode.
+func TestCleanOutput(t *testing.T) {
+	s := "\x1b[33mwarn\x1b[0m 10%\r100%"
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: config.ANSIRaw, want: s},
+		{mode: config.ANSIStrip, want: "warn 10%\r100%"},
+		{mode: config.ANSICollapse, want: "100%"},
+	}
+	for _, tt := range tests {
+		if got := cleanOutput(s, tt.mode); got != tt.want {
+			t.Errorf("cleanOutput(%q, %s) = %q, expected %q", s, tt.mode, got, tt.want)
+		}
+	}
+}
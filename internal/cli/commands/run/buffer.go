@@ -0,0 +1,94 @@
+package run
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// boundedBuffer is an io.Writer which keeps at most a limited number of bytes written to it.
+//
+// When more data is written than the limit allows, the first half of the limit is kept from the start of the data
+// and the second half from the end of the data. A limit of 0 keeps all of the data.
+type boundedBuffer struct {
+	// unexported members
+	head  []byte
+	limit int
+	tail  []byte
+	total int64
+}
+
+// newBoundedBuffer creates a new boundedBuffer object which keeps at most limit bytes.
+func newBoundedBuffer(limit int) *boundedBuffer {
+	return &boundedBuffer{
+		limit: limit,
+	}
+}
+
+// Write appends the data to the buffer, discarding data from the middle if the limit is exceeded.
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return len(p), nil
+	}
+
+	data := p
+	headLimit := b.limit / 2
+	if n := headLimit - len(b.head); n > 0 {
+		if n > len(data) {
+			n = len(data)
+		}
+		b.head = append(b.head, data[:n]...)
+		data = data[n:]
+	}
+
+	// the tail is allowed to grow to twice its limit before it is trimmed so that the data is not copied on
+	// every write
+	tailLimit := b.limit - headLimit
+	b.tail = append(b.tail, data...)
+	if len(b.tail) > 2*tailLimit {
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-tailLimit:]...)
+	}
+	return len(p), nil
+}
+
+// Reset discards all of the data in the buffer.
+func (b *boundedBuffer) Reset() {
+	b.head = b.head[:0]
+	b.tail = b.tail[:0]
+	b.total = 0
+}
+
+// String returns the data kept in the buffer.
+//
+// If data was discarded, a marker indicating how many bytes were discarded is placed between the start and the end
+// of the data.
+func (b *boundedBuffer) String() string {
+	if !b.Truncated() {
+		return string(b.head) + string(b.tail)
+	}
+
+	// avoid splitting multi-byte characters where the data was cut
+	head := b.head
+	for i := 1; i < utf8.UTFMax && len(head) > 0; i++ {
+		if r, size := utf8.DecodeLastRune(head); r != utf8.RuneError || size != 1 {
+			break
+		}
+		head = head[:len(head)-1]
+	}
+	tail := b.tail[len(b.tail)-(b.limit-b.limit/2):]
+	for i := 1; i < utf8.UTFMax && len(tail) > 0 && !utf8.RuneStart(tail[0]); i++ {
+		tail = tail[1:]
+	}
+	return fmt.Sprintf("%s\n[... %d bytes truncated ...]\n%s", head, b.total-int64(len(head)+len(tail)), tail)
+}
+
+// Total returns the total number of bytes written to the buffer.
+func (b *boundedBuffer) Total() int64 {
+	return b.total
+}
+
+// Truncated returns whether or not any data was discarded.
+func (b *boundedBuffer) Truncated() bool {
+	return b.limit > 0 && b.total > int64(b.limit)
+}
@@ -0,0 +1,105 @@
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestBoundedBuffer checks that the start and the end of the data are kept when the limit is exceeded.
+func TestBoundedBuffer(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		writes    []string
+		want      string
+		truncated bool
+	}{
+		{name: "unlimited", limit: 0, writes: []string{"abc", "def"}, want: "abcdef"},
+		{name: "within limit", limit: 4, writes: []string{"ab", "cd"}, want: "abcd"},
+		{name: "single write", limit: 4, writes: []string{"abcdefgh"}, want: "ab\n[... 4 bytes truncated ...]\ngh",
+			truncated: true},
+		{name: "many writes", limit: 4, writes: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"},
+			want: "ab\n[... 6 bytes truncated ...]\nij", truncated: true},
+		{name: "odd limit", limit: 5, writes: []string{"abcdefgh"}, want: "ab\n[... 3 bytes truncated ...]\nfgh",
+			truncated: true},
+		{name: "split characters", limit: 4, writes: []string{"aéxyzéb"}, want: "a\n[... 7 bytes truncated ...]\nb",
+			truncated: true},
+	}
+	for _, tt := range tests {
+		b := newBoundedBuffer(tt.limit)
+		var total int64
+		for _, w := range tt.writes {
+			if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("%s: Write(%q) = %d, %v, expected %d, nil", tt.name, w, n, err, len(w))
+			}
+			total += int64(len(w))
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: String() = %q, expected %q", tt.name, got, tt.want)
+		}
+		if got := b.Total(); got != total {
+			t.Errorf("%s: Total() = %d, expected %d", tt.name, got, total)
+		}
+		if got := b.Truncated(); got != tt.truncated {
+			t.Errorf("%s: Truncated() = %t, expected %t", tt.name, got, tt.truncated)
+		}
+	}
+}
+
+// TestBoundedBufferReset checks that a buffer can be reused after it is reset.
+func TestBoundedBufferReset(t *testing.T) {
+	b := newBoundedBuffer(4)
+	b.Write([]byte("abcdefgh"))
+	b.Reset()
+	b.Write([]byte("xyz"))
+	if got := b.String(); got != "xyz" {
+		t.Errorf("String() = %q, expected %q", got, "xyz")
+	}
+	if b.Total() != 3 || b.Truncated() {
+		t.Errorf("Total() = %d, Truncated() = %t, expected 3, false", b.Total(), b.Truncated())
+	}
+}
+
+// TestOutputCaptureSpill checks that the spill file containing the full output is only kept when the output was
+// truncated.
+func TestOutputCaptureSpill(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		keep   bool
+	}{
+		{name: "not truncated", output: "abcd", keep: false},
+		{name: "truncated", output: "abcdefgh", keep: true},
+	}
+	for _, tt := range tests {
+		c, err := newOutputCapture(config.StreamStdout, 4, t.TempDir(), config.ANSIRaw)
+		if err != nil {
+			t.Fatalf("%s: newOutputCapture() failed: %s", tt.name, err.Error())
+		}
+		c.Write([]byte(tt.output))
+		if err := c.Close(); err != nil {
+			t.Fatalf("%s: Close() failed: %s", tt.name, err.Error())
+		}
+		data, err := os.ReadFile(c.file.Name())
+		if !tt.keep {
+			if !os.IsNotExist(err) {
+				t.Errorf("%s: spill file %s was not removed", tt.name, c.file.Name())
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: failed to read spill file: %s", tt.name, err.Error())
+		}
+		if string(data) != tt.output {
+			t.Errorf("%s: spill file contains %q, expected %q", tt.name, data, tt.output)
+		}
+		sum := sha256.Sum256([]byte(tt.output))
+		if got := hex.EncodeToString(c.hash.Sum(nil)); got != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: sha256 = %s, expected %s", tt.name, got, hex.EncodeToString(sum[:]))
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// cgroupRoot is the mount point of the cgroup v2 hierarchy.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupLeaf is the name of the cgroup json-exec moves itself into when its own cgroup must not contain processes.
+const cgroupLeaf = "json-exec"
+
+// _cgroupControllers is the list of controllers enabled for the cgroup of the command, when available.
+var _cgroupControllers = []string{"cpu", "memory", "pids"}
+
+// cgroup is a cgroup v2 created for the command.
+type cgroup struct {
+	// unexported members
+	cfg     *config.CgroupConfig
+	dir     string
+	enabled []string
+	leaf    string
+	path    string
+}
+
+// newCgroup creates a cgroup for the command and applies its limits.
+func newCgroup(cfg *config.CgroupConfig) (*cgroup, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not mounted at %s", cgroupRoot)
+	}
+	parent := cfg.Parent
+	if parent == "" {
+		own, ok := ownCgroupPath()
+		if !ok {
+			return nil, fmt.Errorf("failed to determine the cgroup of json-exec")
+		}
+		parent = own
+	}
+	parentDir := filepath.Join(cgroupRoot, parent)
+
+	// enable the controllers required by the limits along with any others which are available for accounting
+	required := map[string]bool{
+		"cpu":    cfg.CPUMax != "",
+		"memory": cfg.MemoryMax >= 0,
+		"pids":   cfg.PidsMax > 0,
+	}
+	available, err := os.ReadFile(filepath.Join(parentDir, "cgroup.controllers"))
+	if err != nil {
+		return nil, err
+	}
+	controllers := []string{}
+	for _, controller := range _cgroupControllers {
+		if containsField(string(available), controller) {
+			controllers = append(controllers, controller)
+		} else if required[controller] {
+			return nil, fmt.Errorf("the %s controller is not available in cgroup %s", controller, parent)
+		}
+	}
+	c := &cgroup{
+		cfg:  cfg,
+		dir:  filepath.Join(parentDir, fmt.Sprintf("json-exec-%d", os.Getpid())),
+		path: filepath.Join(parent, fmt.Sprintf("json-exec-%d", os.Getpid())),
+	}
+	if err := c.enableControllers(parentDir, controllers, cfg.Parent == ""); err != nil {
+		c.restore()
+		return nil, err
+	}
+
+	// create the cgroup and apply the limits
+	if err := os.Mkdir(c.dir, 0755); err != nil {
+		c.restore()
+		return nil, err
+	}
+	limits := map[string]string{}
+	if cfg.MemoryMax >= 0 {
+		limits["memory.max"] = strconv.FormatInt(cfg.MemoryMax, 10)
+	}
+	if cfg.CPUMax != "" {
+		limits["cpu.max"] = cfg.CPUMax
+	}
+	if cfg.PidsMax > 0 {
+		limits["pids.max"] = strconv.Itoa(cfg.PidsMax)
+	}
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(c.dir, file), []byte(value), 0644); err != nil {
+			c.Remove()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// enableControllers enables the controllers for the children of the cgroup.
+//
+// A cgroup which contains processes cannot enable controllers for its children. If own is true, the cgroup is the
+// cgroup of json-exec, in which case json-exec moves itself into a leaf cgroup first. The leaf and the controllers
+// which were not already enabled are recorded so that the cgroup can be restored once the command has finished.
+func (c *cgroup) enableControllers(dir string, controllers []string, own bool) error {
+	control := filepath.Join(dir, "cgroup.subtree_control")
+	current, err := os.ReadFile(control)
+	if err != nil {
+		return err
+	}
+	enable := []string{}
+	for _, controller := range controllers {
+		if !containsField(string(current), controller) {
+			enable = append(enable, controller)
+		}
+	}
+	if len(enable) == 0 {
+		return nil
+	}
+
+	value := []byte("+" + strings.Join(enable, " +"))
+	err = os.WriteFile(control, value, 0644)
+	if err != nil && own && errors.Is(err, syscall.EBUSY) {
+		leaf := filepath.Join(dir, cgroupLeaf)
+		if err := os.Mkdir(leaf, 0755); err != nil && !os.IsExist(err) {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())),
+			0644); err != nil {
+			return err
+		}
+		c.leaf = leaf
+		err = os.WriteFile(control, value, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	c.enabled = enable
+	return nil
+}
+
+// Add moves the process into the cgroup.
+func (c *cgroup) Add(pid int) error {
+	return os.WriteFile(filepath.Join(c.dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+}
+
+// AddFields adds the path and limits of the cgroup to the given logging context.
+func (c *cgroup) AddFields(ctx zerolog.Context) zerolog.Context {
+	dict := zerolog.Dict().
+		Str("path", c.path)
+	if c.cfg.MemoryMax >= 0 {
+		dict = dict.Int64("memory_max", c.cfg.MemoryMax)
+	}
+	if c.cfg.CPUMax != "" {
+		dict = dict.Str("cpu_max", c.cfg.CPUMax)
+	}
+	if c.cfg.PidsMax > 0 {
+		dict = dict.Int("pids_max", c.cfg.PidsMax)
+	}
+	return ctx.Dict("cgroup", dict)
+}
+
+// OOMKills returns the number of processes in the cgroup which have been killed by the OOM killer.
+func (c *cgroup) OOMKills() int64 {
+	events, err := readKeyedFile(filepath.Join(c.dir, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	return events["oom_kill"]
+}
+
+// Stats returns the accounting data of the cgroup.
+//
+// Files which do not exist, such as memory.peak on older kernels, are skipped.
+func (c *cgroup) Stats() *zerolog.Event {
+	dict := zerolog.Dict().
+		Str("path", c.path)
+	if data, err := os.ReadFile(filepath.Join(c.dir, "memory.peak")); err == nil {
+		if peak, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			dict = dict.Int64("memory_peak_bytes", peak)
+		}
+	}
+	for _, file := range []string{"cpu.stat", "memory.events"} {
+		values, err := readKeyedFile(filepath.Join(c.dir, file))
+		if err != nil {
+			continue
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		stats := zerolog.Dict()
+		for _, key := range keys {
+			stats = stats.Int64(key, values[key])
+		}
+		dict = dict.Dict(strings.ReplaceAll(file, ".", "_"), stats)
+	}
+	return dict
+}
+
+// Remove removes the cgroup and restores the cgroup of json-exec.
+//
+// The cgroup cannot be removed while it still contains processes, such as daemons started by the command, in which
+// case json-exec is left in its leaf cgroup as well.
+func (c *cgroup) Remove() error {
+	if err := os.Remove(c.dir); err != nil {
+		return err
+	}
+	return c.restore()
+}
+
+// restore disables the controllers enabled for the cgroup of the command and moves json-exec back out of its leaf
+// cgroup, removing the leaf unless it is still used by another instance of json-exec.
+//
+// Other instances of json-exec running under the same parent may depend on the controllers, so they are only disabled
+// once no other child cgroups remain. Otherwise, json-exec is left in its leaf cgroup since a cgroup which enables
+// controllers for its children cannot contain processes.
+func (c *cgroup) restore() error {
+	if c.leaf == "" {
+		return nil
+	}
+	dir := filepath.Dir(c.leaf)
+	if len(c.enabled) > 0 {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Name() != cgroupLeaf {
+				return nil
+			}
+		}
+		value := []byte("-" + strings.Join(c.enabled, " -"))
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), value, 0644); err != nil {
+			// another instance of json-exec created its cgroup in the meantime
+			if errors.Is(err, syscall.EBUSY) {
+				return nil
+			}
+			return err
+		}
+		c.enabled = nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		// the controllers were enabled by another instance of json-exec which is still running
+		if errors.Is(err, syscall.EBUSY) {
+			return nil
+		}
+		return err
+	}
+	leaf := c.leaf
+	c.leaf = ""
+	if err := os.Remove(leaf); err != nil && !errors.Is(err, syscall.EBUSY) {
+		return err
+	}
+	return nil
+}
+
+// ownCgroupPath returns the path of the cgroup v2 of json-exec relative to the root of the hierarchy.
+func ownCgroupPath() (string, bool) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), true
+		}
+	}
+	return "", false
+}
+
+// readKeyedFile reads a cgroup file containing a "key value" pair on each line.
+func readKeyedFile(path string) (map[string]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]int64{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, nil
+}
+
+// containsField returns whether or not the whitespace-separated list contains the given field.
+func containsField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestReadKeyedFile checks that "key value" pairs are read from cgroup files, skipping malformed lines.
+func TestReadKeyedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "memory.events")
+	data := "low 0\nhigh 12\noom_kill 3\nmalformed\nnot_a_number x\nextra 1 2\n"
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to create file: %s", err.Error())
+	}
+	values, err := readKeyedFile(file)
+	if err != nil {
+		t.Fatalf("readKeyedFile() failed: %s", err.Error())
+	}
+	if want := map[string]int64{"low": 0, "high": 12, "oom_kill": 3}; !reflect.DeepEqual(values, want) {
+		t.Errorf("readKeyedFile() = %v, expected %v", values, want)
+	}
+	if _, err := readKeyedFile(file + ".missing"); err == nil {
+		t.Errorf("readKeyedFile() of a missing file succeeded")
+	}
+}
+
+// TestContainsField checks that only whole fields of whitespace-separated lists match.
+func TestContainsField(t *testing.T) {
+	tests := []struct {
+		s     string
+		field string
+		want  bool
+	}{
+		{s: "cpuset cpu io memory pids\n", field: "cpu", want: true},
+		{s: "cpuset io memory pids\n", field: "cpu", want: false},
+		{s: "memory", field: "memory", want: true},
+		{s: "", field: "pids", want: false},
+	}
+	for _, tt := range tests {
+		if got := containsField(tt.s, tt.field); got != tt.want {
+			t.Errorf("containsField(%q, %s) = %t, expected %t", tt.s, tt.field, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// cgroup is not supported on this platform.
+type cgroup struct{}
+
+// newCgroup always returns an error since cgroups are only supported on Linux.
+func newCgroup(cfg *config.CgroupConfig) (*cgroup, error) {
+	return nil, fmt.Errorf("cgroups are only supported on Linux")
+}
+
+// Add does nothing.
+func (c *cgroup) Add(pid int) error {
+	return nil
+}
+
+// AddFields does nothing.
+func (c *cgroup) AddFields(ctx zerolog.Context) zerolog.Context {
+	return ctx
+}
+
+// OOMKills always returns 0.
+func (c *cgroup) OOMKills() int64 {
+	return 0
+}
+
+// Stats always returns an empty dictionary.
+func (c *cgroup) Stats() *zerolog.Event {
+	return zerolog.Dict()
+}
+
+// Remove does nothing.
+func (c *cgroup) Remove() error {
+	return nil
+}
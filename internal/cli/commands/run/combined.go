@@ -0,0 +1,145 @@
+package run
+
+import (
+	"sync"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// outputChunk is a chunk of output from a single stream of the command.
+type outputChunk struct {
+	// unexported members
+	data   []byte
+	seq    int
+	stream string
+}
+
+// combinedCapture captures the output from both streams of the command in the order in which it is received.
+//
+// Each stream is read from its own pipe by its own goroutine, so output written to both streams in quick succession
+// may be received in a different order than it was written.
+//
+// Consecutive writes to the same stream are combined into a single chunk. At most limit bytes of output are kept in
+// memory: once the limit is exceeded, the chunks containing the first half of the limit are kept along with the
+// chunks containing the last half. A limit of 0 keeps all of the output.
+type combinedCapture struct {
+	// unexported members
+	ansi      string
+	head      []outputChunk
+	headBytes int
+	last      string
+	limit     int
+	mu        sync.Mutex
+	seq       int
+	tail      []outputChunk
+	tailBytes int
+	total     int64
+}
+
+// combinedWriter is an io.Writer which writes the output of a single stream to a combinedCapture.
+type combinedWriter struct {
+	// unexported members
+	capture *combinedCapture
+	stream  string
+}
+
+// newCombinedCapture creates a new combinedCapture object with the given limit.
+//
+// The ANSI mode determines how escape sequences and carriage returns in each chunk are handled.
+func newCombinedCapture(limit int, ansi string) *combinedCapture {
+	return &combinedCapture{
+		ansi:  ansi,
+		limit: limit,
+	}
+}
+
+// Writer returns an io.Writer which captures output from the given stream.
+func (c *combinedCapture) Writer(stream string) *combinedWriter {
+	return &combinedWriter{
+		capture: c,
+		stream:  stream,
+	}
+}
+
+// Write captures the data written to the stream.
+func (w *combinedWriter) Write(p []byte) (int, error) {
+	w.capture.write(w.stream, p)
+	return len(p), nil
+}
+
+// AddFields adds the combined output and details about it to the logger context.
+func (c *combinedCapture) AddFields(ctx zerolog.Context) zerolog.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := zerolog.Arr()
+	for _, chunks := range [][]outputChunk{c.head, c.tail} {
+		for _, chunk := range chunks {
+			output = output.Dict(zerolog.Dict().
+				Int("seq", chunk.seq).
+				Str("stream", chunk.stream).
+				Str("data", cleanOutput(string(chunk.data), c.ansi)))
+		}
+	}
+	ctx = ctx.Array("output", output)
+	if c.limit > 0 && c.total > int64(c.limit) {
+		ctx = ctx.
+			Bool("output_truncated", true).
+			Int64("output_total_bytes", c.total)
+	}
+	return ctx
+}
+
+// write adds the data to the captured output.
+func (c *combinedCapture) write(stream string, p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total += int64(len(p))
+	if stream != c.last {
+		c.seq++
+		c.last = stream
+	}
+	if c.limit <= 0 {
+		c.head = appendChunk(c.head, c.seq, stream, p)
+		return
+	}
+
+	// fill the head first and then keep only the most recent output in the tail
+	if room := c.limit/2 - c.headBytes; room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		c.head = appendChunk(c.head, c.seq, stream, p[:room])
+		c.headBytes += room
+		p = p[room:]
+	}
+	if len(p) == 0 {
+		return
+	}
+	c.tail = appendChunk(c.tail, c.seq, stream, p)
+	c.tailBytes += len(p)
+	for tailLimit := c.limit - c.limit/2; c.tailBytes > tailLimit; {
+		excess := c.tailBytes - tailLimit
+		if len(c.tail[0].data) <= excess {
+			c.tailBytes -= len(c.tail[0].data)
+			c.tail = c.tail[1:]
+		} else {
+			c.tail[0].data = c.tail[0].data[excess:]
+			c.tailBytes -= excess
+		}
+	}
+}
+
+// appendChunk adds the data to the last chunk if it has the same sequence number or as a new chunk otherwise.
+func appendChunk(chunks []outputChunk, seq int, stream string, p []byte) []outputChunk {
+	if n := len(chunks); n > 0 && chunks[n-1].seq == seq {
+		chunks[n-1].data = append(chunks[n-1].data, p...)
+		return chunks
+	}
+	return append(chunks, outputChunk{
+		data:   append([]byte(nil), p...),
+		seq:    seq,
+		stream: stream,
+	})
+}
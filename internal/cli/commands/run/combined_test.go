@@ -0,0 +1,60 @@
+package run
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestCombinedCapture checks that output from both streams is kept in order, with consecutive writes to the same
+// stream combined into a single chunk and output discarded from the middle once the limit is exceeded.
+func TestCombinedCapture(t *testing.T) {
+	type write struct {
+		stream string
+		data   string
+	}
+	out := func(data string) write { return write{stream: config.StreamStdout, data: data} }
+	errs := func(data string) write { return write{stream: config.StreamStderr, data: data} }
+
+	tests := []struct {
+		name   string
+		limit  int
+		writes []write
+		want   []string
+	}{
+		{name: "unlimited", writes: []write{out("a"), out("b"), errs("c"), out("d")},
+			want: []string{"1 stdout ab", "2 stderr c", "3 stdout d"}},
+		{name: "within limit", limit: 16, writes: []write{out("ab"), errs("cd"), errs("ef")},
+			want: []string{"1 stdout ab", "2 stderr cdef"}},
+		{name: "truncated", limit: 4, writes: []write{out("abc"), errs("d"), out("e")},
+			want: []string{"1 stdout ab", "2 stderr d", "3 stdout e"}},
+		{name: "tail trimmed", limit: 4, writes: []write{out("ab"), errs("cd"), out("efgh")},
+			want: []string{"1 stdout ab", "3 stdout gh"}},
+		{name: "chunk split", limit: 8, writes: []write{out("ab"), errs("cd"), errs("ef")},
+			want: []string{"1 stdout ab", "2 stderr cd", "2 stderr ef"}},
+		{name: "single write", limit: 4, writes: []write{errs("abcdefgh")},
+			want: []string{"1 stderr ab", "1 stderr gh"}},
+	}
+	for _, tt := range tests {
+		c := newCombinedCapture(tt.limit, config.ANSIRaw)
+		var total int64
+		for _, w := range tt.writes {
+			c.Writer(w.stream).Write([]byte(w.data))
+			total += int64(len(w.data))
+		}
+		got := []string{}
+		for _, chunks := range [][]outputChunk{c.head, c.tail} {
+			for _, chunk := range chunks {
+				got = append(got, fmt.Sprintf("%d %s %s", chunk.seq, chunk.stream, chunk.data))
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: chunks = %q, expected %q", tt.name, got, tt.want)
+		}
+		if c.total != total {
+			t.Errorf("%s: total = %d, expected %d", tt.name, c.total, total)
+		}
+	}
+}
@@ -1,15 +1,20 @@
 package run
 
 import (
-	"bytes"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.sophtrust.dev/json-exec/internal/app"
 	"go.sophtrust.dev/json-exec/internal/config"
 	"go.sophtrust.dev/json-exec/internal/errors"
+	"go.sophtrust.dev/json-exec/internal/signals"
+	"go.sophtrust.dev/pkg/zerolog/v2"
 	"go.sophtrust.dev/pkg/zerolog/v2/log"
 )
 
@@ -44,6 +49,11 @@ to use -- before the system command when it requires its own set of flags.`,
 	viper := config.Viper()
 	flags := cmd.Flags()
 
+	flags.Bool("combined", false, "also capture the output of both streams in the order it was received, "+
+		"which is best-effort across streams")
+	viper.SetDefault("run.combined", false)
+	viper.BindPFlag("run.combined", flags.Lookup("combined"))
+
 	flags.Bool("ignore-stdout", false, "ignore stdout output from the command")
 	viper.SetDefault("run.ignore_stdout", false)
 	viper.BindPFlag("run.ignore_stdout", flags.Lookup("ignore-stdout"))
@@ -52,6 +62,342 @@ to use -- before the system command when it requires its own set of flags.`,
 	viper.SetDefault("run.ignore_stderr", false)
 	viper.BindPFlag("run.ignore_stderr", flags.Lookup("ignore-stderr"))
 
+	flags.Int("max-output-bytes", 0,
+		"maximum number of bytes of output to keep from each stream (or each line when streaming) - 0 keeps everything")
+	viper.SetDefault("run.max_output_bytes", 0)
+	viper.BindPFlag("run.max_output_bytes", flags.Lookup("max-output-bytes"))
+
+	flags.Bool("spill-output", false, "write the full output of each stream to a file when it is truncated")
+	viper.SetDefault("run.spill_output", false)
+	viper.BindPFlag("run.spill_output", flags.Lookup("spill-output"))
+
+	flags.String("spill-dir", "", "directory in which to write the full output (default is the system temp directory)")
+	viper.SetDefault("run.spill_dir", "")
+	viper.BindPFlag("run.spill_dir", flags.Lookup("spill-dir"))
+
+	flags.String("parse", config.ParseFormatNone,
+		"format of output lines to parse into fields when streaming - must be one of: none, json or logfmt")
+	viper.SetDefault("run.parse.format", config.ParseFormatNone)
+	viper.BindPFlag("run.parse.format", flags.Lookup("parse"))
+
+	flags.String("parse-namespace", "", "name of the field under which to nest parsed fields")
+	viper.SetDefault("run.parse.namespace", "")
+	viper.BindPFlag("run.parse.namespace", flags.Lookup("parse-namespace"))
+
+	flags.String("parse-conflict-policy", config.ConflictPolicyRename,
+		"how to handle parsed fields which conflict with reserved fields - must be one of: drop or rename")
+	viper.SetDefault("run.parse.conflict_policy", config.ConflictPolicyRename)
+	viper.BindPFlag("run.parse.conflict_policy", flags.Lookup("parse-conflict-policy"))
+
+	flags.String("parse-key-prefix", "", "prefix to add to the name of every parsed field")
+	viper.SetDefault("run.parse.key_prefix", "")
+	viper.BindPFlag("run.parse.key_prefix", flags.Lookup("parse-key-prefix"))
+
+	flags.String("logfmt-quoting", config.QuotingDouble,
+		"quotes which may surround logfmt values - must be one of: any, double or none")
+	viper.SetDefault("run.parse.logfmt.quoting", config.QuotingDouble)
+	viper.BindPFlag("run.parse.logfmt.quoting", flags.Lookup("logfmt-quoting"))
+
+	flags.String("logfmt-duplicate-keys", config.DuplicateKeysLast,
+		"how to handle logfmt keys which appear more than once - must be one of: array, first or last")
+	viper.SetDefault("run.parse.logfmt.duplicate_keys", config.DuplicateKeysLast)
+	viper.BindPFlag("run.parse.logfmt.duplicate_keys", flags.Lookup("logfmt-duplicate-keys"))
+
+	flags.Bool("parse-passthrough", true, "use the level and message from parsed fields for the output line")
+	viper.SetDefault("run.parse.passthrough", true)
+	viper.BindPFlag("run.parse.passthrough", flags.Lookup("parse-passthrough"))
+
+	viper.SetDefault("run.parse.conflict_prefix", config.DefaultParseConflictPrefix)
+	viper.SetDefault("run.parse.level_fields", config.DefaultParseLevelFields)
+	viper.SetDefault("run.parse.message_fields", config.DefaultParseMessageFields)
+	viper.SetDefault("run.parse.commands", nil)
+
+	viper.SetDefault("run.extract", nil)
+	viper.SetDefault("run.patterns", nil)
+
+	flags.String("stdout-level", "info", "level of streamed stdout lines which do not match a level rule")
+	viper.SetDefault("run.levels.stdout", "info")
+	viper.BindPFlag("run.levels.stdout", flags.Lookup("stdout-level"))
+
+	flags.String("stderr-level", "warn", "level of streamed stderr lines which do not match a level rule")
+	viper.SetDefault("run.levels.stderr", "warn")
+	viper.BindPFlag("run.levels.stderr", flags.Lookup("stderr-level"))
+
+	viper.SetDefault("run.levels.rules", nil)
+
+	flags.String("multiline", config.MultilinePresetNone,
+		"preset for grouping multiline output when streaming - must be one of: none, go, java, node or python")
+	viper.SetDefault("run.multiline.preset", config.MultilinePresetNone)
+	viper.BindPFlag("run.multiline.preset", flags.Lookup("multiline"))
+
+	flags.String("multiline-start", "", "regular expression matching lines which start a new group of lines")
+	viper.SetDefault("run.multiline.start", "")
+	viper.BindPFlag("run.multiline.start", flags.Lookup("multiline-start"))
+
+	flags.String("multiline-continuation", "", "regular expression matching lines which continue the current group")
+	viper.SetDefault("run.multiline.continuation", "")
+	viper.BindPFlag("run.multiline.continuation", flags.Lookup("multiline-continuation"))
+
+	flags.Int("multiline-max-lines", 500, "maximum number of lines in a group - 0 does not limit the number of lines")
+	viper.SetDefault("run.multiline.max_lines", 500)
+	viper.BindPFlag("run.multiline.max_lines", flags.Lookup("multiline-max-lines"))
+
+	flags.Duration("multiline-max-wait", time.Second,
+		"maximum amount of time to wait for another line before emitting a group - 0 waits for the next line")
+	viper.SetDefault("run.multiline.max_wait", "1s")
+	viper.BindPFlag("run.multiline.max_wait", flags.Lookup("multiline-max-wait"))
+
+	flags.Bool("pty", false, "run the command in a pseudo-terminal - stderr is merged into stdout")
+	viper.SetDefault("run.pty.enabled", false)
+	viper.BindPFlag("run.pty.enabled", flags.Lookup("pty"))
+
+	flags.Int("pty-cols", config.DefaultPtyCols, "number of columns in the pseudo-terminal window")
+	viper.SetDefault("run.pty.cols", config.DefaultPtyCols)
+	viper.BindPFlag("run.pty.cols", flags.Lookup("pty-cols"))
+
+	flags.Int("pty-rows", config.DefaultPtyRows, "number of rows in the pseudo-terminal window")
+	viper.SetDefault("run.pty.rows", config.DefaultPtyRows)
+	viper.BindPFlag("run.pty.rows", flags.Lookup("pty-rows"))
+
+	flags.String("ansi", config.ANSIAuto, "how to handle ANSI escape sequences and carriage returns in the output - "+
+		"must be one of: auto, collapse, raw or strip")
+	viper.SetDefault("run.ansi", config.ANSIAuto)
+	viper.BindPFlag("run.ansi", flags.Lookup("ansi"))
+
+	flags.Bool("resources", true, "include timing and resource usage of the command in the result")
+	viper.SetDefault("run.resources", true)
+	viper.BindPFlag("run.resources", flags.Lookup("resources"))
+
+	flags.Duration("sample-interval", 0,
+		"interval at which to sample the resource usage of the command and its descendants - 0 disables sampling")
+	viper.SetDefault("run.sample_interval", "0s")
+	viper.BindPFlag("run.sample_interval", flags.Lookup("sample-interval"))
+
+	flags.String("stdin", "", "how to provide stdin to the command - must be one of: close, data, file or forward "+
+		"(default is file or data if either is given, otherwise close)")
+	viper.SetDefault("run.stdin.mode", "")
+	viper.BindPFlag("run.stdin.mode", flags.Lookup("stdin"))
+
+	flags.String("stdin-file", "", "path to the file to feed to the command as stdin")
+	viper.SetDefault("run.stdin.file", "")
+	viper.BindPFlag("run.stdin.file", flags.Lookup("stdin-file"))
+
+	flags.String("stdin-data", "", "literal string to feed to the command as stdin")
+	viper.SetDefault("run.stdin.data", "")
+	viper.BindPFlag("run.stdin.data", flags.Lookup("stdin-data"))
+
+	flags.Bool("record-stdin", false, "record the number of bytes and SHA-256 checksum of stdin")
+	viper.SetDefault("run.stdin.record", false)
+	viper.BindPFlag("run.stdin.record", flags.Lookup("record-stdin"))
+
+	flags.Bool("record-stdin-content", false, "record the content of stdin as well")
+	viper.SetDefault("run.stdin.record_content", false)
+	viper.BindPFlag("run.stdin.record_content", flags.Lookup("record-stdin-content"))
+
+	flags.Bool("stream", false, "emit a message for each line of output as it is produced")
+	viper.SetDefault("run.stream", false)
+	viper.BindPFlag("run.stream", flags.Lookup("stream"))
+
+	flags.StringSlice("forward-signal", []string{"HUP", "INT", "QUIT", "TERM"},
+		"signal to forward to the command when it is received - may be specified more than once")
+	viper.SetDefault("run.forward_signals", []string{"HUP", "INT", "QUIT", "TERM"})
+	viper.BindPFlag("run.forward_signals", flags.Lookup("forward-signal"))
+
+	flags.StringArray("env", nil, "environment variable to set for the command in KEY=VALUE format (or KEY to "+
+		"copy its value) - may be specified more than once")
+	viper.SetDefault("run.env.vars", nil)
+	viper.BindPFlag("run.env.vars", flags.Lookup("env"))
+
+	flags.StringArray("env-file", nil, "dotenv file containing environment variables to set for the command - "+
+		"may be specified more than once")
+	viper.SetDefault("run.env.files", nil)
+	viper.BindPFlag("run.env.files", flags.Lookup("env-file"))
+
+	flags.Bool("clear-env", false, "do not inherit any environment variables")
+	viper.SetDefault("run.env.clear", false)
+	viper.BindPFlag("run.env.clear", flags.Lookup("clear-env"))
+
+	flags.StringSlice("env-allow", nil, "pattern matching the names of environment variables to inherit - "+
+		"may be specified more than once")
+	viper.SetDefault("run.env.allow", nil)
+	viper.BindPFlag("run.env.allow", flags.Lookup("env-allow"))
+
+	flags.StringSlice("env-deny", nil, "pattern matching the names of environment variables not to inherit - "+
+		"may be specified more than once")
+	viper.SetDefault("run.env.deny", nil)
+	viper.BindPFlag("run.env.deny", flags.Lookup("env-deny"))
+
+	flags.Bool("record-env", false, "record the names of the environment variables passed to the command")
+	viper.SetDefault("run.env.record", false)
+	viper.BindPFlag("run.env.record", flags.Lookup("record-env"))
+
+	flags.StringSlice("record-env-value", nil, "pattern matching the names of environment variables whose values "+
+		"are recorded rather than redacted - may be specified more than once")
+	viper.SetDefault("run.env.record_values", nil)
+	viper.BindPFlag("run.env.record_values", flags.Lookup("record-env-value"))
+
+	flags.String("cwd", "", "working directory of the command (default is the working directory of json-exec)")
+	viper.SetDefault("run.process.cwd", "")
+	viper.BindPFlag("run.process.cwd", flags.Lookup("cwd"))
+
+	flags.String("user", "", "name or ID of the user to run the command as (default is the user of json-exec)")
+	viper.SetDefault("run.process.user", "")
+	viper.BindPFlag("run.process.user", flags.Lookup("user"))
+
+	flags.String("group", "", "name or ID of the primary group of the command (default is the primary group of the "+
+		"user)")
+	viper.SetDefault("run.process.group", "")
+	viper.BindPFlag("run.process.group", flags.Lookup("group"))
+
+	flags.StringSlice("supplementary-group", nil, "name or ID of a supplementary group of the command - may be "+
+		"specified more than once (default is the supplementary groups of the user)")
+	viper.SetDefault("run.process.groups", nil)
+	viper.BindPFlag("run.process.groups", flags.Lookup("supplementary-group"))
+
+	flags.String("umask", "", "octal file mode creation mask of the command (default is the umask of json-exec)")
+	viper.SetDefault("run.process.umask", "")
+	viper.BindPFlag("run.process.umask", flags.Lookup("umask"))
+
+	flags.Int("nice", 0, "adjustment added to the niceness of json-exec for the command - negative values "+
+		"require privileges")
+	viper.SetDefault("run.process.nice", 0)
+	viper.BindPFlag("run.process.nice", flags.Lookup("nice"))
+
+	flags.String("ionice-class", "", "I/O scheduling class of the command - must be one of: realtime, best-effort "+
+		"or idle (default is the class of json-exec)")
+	viper.SetDefault("run.process.ionice_class", "")
+	viper.BindPFlag("run.process.ionice_class", flags.Lookup("ionice-class"))
+
+	flags.Int("ionice-level", config.DefaultIONiceLevel, "I/O scheduling priority level of the command from 0 "+
+		"(highest) to 7 (lowest) for the realtime and best-effort classes")
+	viper.SetDefault("run.process.ionice_level", config.DefaultIONiceLevel)
+	viper.BindPFlag("run.process.ionice_level", flags.Lookup("ionice-level"))
+
+	flags.String("cpu-affinity", "", "list of CPUs on which the command may run (eg: 0-3,6)")
+	viper.SetDefault("run.process.cpu_affinity", "")
+	viper.BindPFlag("run.process.cpu_affinity", flags.Lookup("cpu-affinity"))
+
+	flags.Duration("limit-cpu", 0, "maximum amount of CPU time the command may use before it receives SIGXCPU - "+
+		"0 does not limit CPU time")
+	viper.SetDefault("run.limits.cpu", "0s")
+	viper.BindPFlag("run.limits.cpu", flags.Lookup("limit-cpu"))
+
+	flags.String("limit-address-space", "", "maximum size of the virtual memory of the command in bytes (eg: 512M)")
+	viper.SetDefault("run.limits.address_space", "")
+	viper.BindPFlag("run.limits.address_space", flags.Lookup("limit-address-space"))
+
+	flags.Int("limit-open-files", 0, "maximum number of files the command may have open at once - 0 does not "+
+		"limit open files")
+	viper.SetDefault("run.limits.open_files", 0)
+	viper.BindPFlag("run.limits.open_files", flags.Lookup("limit-open-files"))
+
+	flags.Int("limit-processes", 0, "maximum number of processes which may run as the user of the command - 0 does "+
+		"not limit processes")
+	viper.SetDefault("run.limits.processes", 0)
+	viper.BindPFlag("run.limits.processes", flags.Lookup("limit-processes"))
+
+	flags.String("limit-file-size", "", "maximum size of a file written by the command in bytes (eg: 1G)")
+	viper.SetDefault("run.limits.file_size", "")
+	viper.BindPFlag("run.limits.file_size", flags.Lookup("limit-file-size"))
+
+	flags.String("limit-core", "", "maximum size of a core file dumped by the command in bytes - 0 disables core "+
+		"dumps")
+	viper.SetDefault("run.limits.core", "")
+	viper.BindPFlag("run.limits.core", flags.Lookup("limit-core"))
+
+	flags.Bool("cgroup", false, "run the command in its own cgroup v2 subtree and include its accounting data in the "+
+		"result")
+	viper.SetDefault("run.cgroup.enabled", false)
+	viper.BindPFlag("run.cgroup.enabled", flags.Lookup("cgroup"))
+
+	flags.String("cgroup-parent", "", "path of the cgroup under which to create the cgroup of the command "+
+		"(default is the cgroup of json-exec)")
+	viper.SetDefault("run.cgroup.parent", "")
+	viper.BindPFlag("run.cgroup.parent", flags.Lookup("cgroup-parent"))
+
+	flags.String("cgroup-memory-max", "", "memory limit of the cgroup of the command in bytes (eg: 512M)")
+	viper.SetDefault("run.cgroup.memory_max", "")
+	viper.BindPFlag("run.cgroup.memory_max", flags.Lookup("cgroup-memory-max"))
+
+	flags.String("cgroup-cpu-max", "", "CPU limit of the cgroup of the command as a number of CPUs (eg: 1.5) or a "+
+		"quota and period in microseconds")
+	viper.SetDefault("run.cgroup.cpu_max", "")
+	viper.BindPFlag("run.cgroup.cpu_max", flags.Lookup("cgroup-cpu-max"))
+
+	flags.Int("cgroup-pids-max", 0, "maximum number of processes in the cgroup of the command - 0 does not limit "+
+		"processes")
+	viper.SetDefault("run.cgroup.pids_max", 0)
+	viper.BindPFlag("run.cgroup.pids_max", flags.Lookup("cgroup-pids-max"))
+
+	flags.Bool("sandbox", false, "run the command in new user, mount, PID, network and IPC namespaces")
+	viper.SetDefault("run.sandbox.enabled", false)
+	viper.BindPFlag("run.sandbox.enabled", flags.Lookup("sandbox"))
+
+	flags.Bool("sandbox-network", false, "allow the sandboxed command to use the network of the host")
+	viper.SetDefault("run.sandbox.network", false)
+	viper.BindPFlag("run.sandbox.network", flags.Lookup("sandbox-network"))
+
+	flags.Bool("sandbox-private-tmp", true, "give the sandboxed command its own empty /tmp")
+	viper.SetDefault("run.sandbox.private_tmp", true)
+	viper.BindPFlag("run.sandbox.private_tmp", flags.Lookup("sandbox-private-tmp"))
+
+	flags.StringSlice("sandbox-read-only", nil, "path to make read-only in the sandbox, or SOURCE:TARGET to mount "+
+		"the source read-only at the target - may be specified more than once")
+	viper.SetDefault("run.sandbox.read_only", nil)
+	viper.BindPFlag("run.sandbox.read_only", flags.Lookup("sandbox-read-only"))
+
+	flags.StringSlice("landlock-read-only", nil, "path beneath which the command may only read and execute files, "+
+		"denying access to any other files - may be specified more than once")
+	viper.SetDefault("run.landlock.read_only", nil)
+	viper.BindPFlag("run.landlock.read_only", flags.Lookup("landlock-read-only"))
+
+	flags.StringSlice("landlock-read-write", nil, "path beneath which the command may read, write, execute, "+
+		"create and remove files, denying access to any other files - may be specified more than once")
+	viper.SetDefault("run.landlock.read_write", nil)
+	viper.BindPFlag("run.landlock.read_write", flags.Lookup("landlock-read-write"))
+
+	flags.StringSlice("seccomp-allow", nil, "name of a system call the command may make, denying all others - may "+
+		"be specified more than once")
+	viper.SetDefault("run.seccomp.allow", nil)
+	viper.BindPFlag("run.seccomp.allow", flags.Lookup("seccomp-allow"))
+
+	flags.StringSlice("seccomp-deny", nil, "name of a system call the command may not make - may be specified more "+
+		"than once")
+	viper.SetDefault("run.seccomp.deny", nil)
+	viper.BindPFlag("run.seccomp.deny", flags.Lookup("seccomp-deny"))
+
+	flags.StringSlice("seccomp-profile", nil, "built-in profile of system calls the command may not make - must be "+
+		"one of: no-network or no-ptrace - may be specified more than once")
+	viper.SetDefault("run.seccomp.profiles", nil)
+	viper.BindPFlag("run.seccomp.profiles", flags.Lookup("seccomp-profile"))
+
+	flags.String("seccomp-action", config.SeccompActionErrno, "action taken when the command makes a system call "+
+		"which is not allowed - must be one of: errno or kill")
+	viper.SetDefault("run.seccomp.action", config.SeccompActionErrno)
+	viper.BindPFlag("run.seccomp.action", flags.Lookup("seccomp-action"))
+
+	flags.Bool("forward-to-group", false, "forward signals to the entire process group of the command")
+	viper.SetDefault("run.forward_to_group", false)
+	viper.BindPFlag("run.forward_to_group", flags.Lookup("forward-to-group"))
+
+	flags.Bool("init", false, "act as an init process by reaping orphaned processes started by the command")
+	viper.SetDefault("run.init", false)
+	viper.BindPFlag("run.init", flags.Lookup("init"))
+
+	flags.Duration("timeout", 0,
+		"maximum amount of time the command may run before it is signalled - 0 disables the timeout")
+	viper.SetDefault("run.timeout", "0s")
+	viper.BindPFlag("run.timeout", flags.Lookup("timeout"))
+
+	flags.Duration("kill-after", 10*time.Second,
+		"amount of time to wait after the timeout signal is sent before killing the command - 0 disables killing")
+	viper.SetDefault("run.kill_after", "10s")
+	viper.BindPFlag("run.kill_after", flags.Lookup("kill-after"))
+
+	flags.String("timeout-signal", "TERM", "signal to send to the command when the timeout expires")
+	viper.SetDefault("run.timeout_signal", "TERM")
+	viper.BindPFlag("run.timeout_signal", flags.Lookup("timeout-signal"))
+
 	return cmd
 }
 
@@ -66,29 +412,307 @@ func (c *Command) run(cmd *cobra.Command, args []string) {
 	defer restoreLogger()
 	cfg := config.Get()
 
-	// run the command
-	var stdout, stderr bytes.Buffer
+	// make sure the command may be executed before preparing anything for it
+	var decision *policyDecision
+	if cfg.Policy.Enabled() {
+		d, err := evaluatePolicy(&cfg.Policy, args, cfg.Run.Process.Cwd)
+		if err != nil {
+			log.Error().Err(err).
+				Int("exit_code", errors.CommandDenied).
+				Str("error_type", startErrorPolicyDenied).
+				Msgf("command denied since the policy could not be evaluated: %s", err.Error())
+			c.main.SetExitCode(errors.CommandDenied)
+			return
+		}
+		if !d.allowed {
+			logger := d.AddFields(log.With()).Logger()
+			logger.Error().
+				Int("exit_code", errors.CommandDenied).
+				Str("error_type", startErrorPolicyDenied).
+				Msgf("command denied by %s", d.Reason())
+			c.main.SetExitCode(errors.CommandDenied)
+			return
+		}
+		decision = d
+		defer decision.Close()
+	}
+
+	// configure the environment of the command
+	env, err := buildEnvironment(&cfg.Run.Env)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to configure environment: %s", err.Error())
+		c.main.SetExitCode(errors.GeneralFailure)
+		return
+	}
+
+	// place the command in its own cgroup
+	var cg *cgroup
+	if cfg.Run.Cgroup.Enabled {
+		if cg, err = newCgroup(&cfg.Run.Cgroup); err != nil {
+			log.Warn().Err(err).Msgf("failed to create cgroup, continuing without it: %s", err.Error())
+		} else {
+			defer func() {
+				if err := cg.Remove(); err != nil {
+					log.Warn().Err(err).Msgf("failed to remove cgroup: %s", err.Error())
+				}
+			}()
+		}
+	}
+
+	// configure the execution context of the command
+	launcher, err := newLauncher(&cfg.Run, cg)
+	if err != nil {
+		exitCode := errors.GeneralFailure
+		event := log.Error().Err(err)
+		if settingErr, ok := err.(*settingError); ok {
+			event = event.Str("setting", settingErr.setting)
+			if settingErr.setting == settingWorkingDirectory {
+				exitCode = errors.CommandCannotExecute
+				event = event.
+					Int("exit_code", exitCode).
+					Str("error_type", startErrorBadWorkingDirectory)
+			}
+		}
+		event.Msgf("failed to configure execution context: %s", err.Error())
+		c.main.SetExitCode(exitCode)
+		return
+	}
+	defer launcher.Close()
+
+	// configure the input of the command
+	stdin, err := newStdinSource(&cfg.Run.Stdin, cfg.Run.MaxOutputBytes)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to configure stdin: %s", err.Error())
+		c.main.SetExitCode(errors.GeneralFailure)
+		return
+	}
+	defer stdin.Close()
+
+	// configure the output of the command
+	spillDir := ""
+	if cfg.Run.SpillOutput {
+		spillDir = cfg.Run.SpillDir
+		if spillDir == "" {
+			spillDir = os.TempDir()
+		}
+	}
+	var stdout, stderr *outputCapture
+	var stdoutLines, stderrLines *lineWriter
+	commandPath, _ := exec.LookPath(args[0])
+	processor := newLineProcessor(cfg, cfg.Run.Parse.ForCommand(args[0], commandPath))
 	command := exec.Command(args[0], args[1:]...)
+	if decision != nil && decision.path != "" {
+		// execute the binary which was checked against the policy rather than looking it up again
+		command.Path = decision.path
+		decision.Pin(command)
+	}
+	if cfg.Run.Env.Customized() {
+		command.Env = env.List()
+	}
 	if cfg.Run.IgnoreStdout {
 		command.Stdout = nil
+	} else if cfg.Run.Stream {
+		stdoutLines = newLineWriter("stdout", cfg.Run.MaxOutputBytes, processor, &cfg.Run.Multiline)
+		command.Stdout = stdoutLines
 	} else {
-		command.Stdout = &stdout
+		if stdout, err = newOutputCapture("stdout", cfg.Run.MaxOutputBytes, spillDir, cfg.Run.ANSI); err != nil {
+			log.Error().Err(err).Msgf("failed to capture output: %s", err.Error())
+			c.main.SetExitCode(errors.GeneralFailure)
+			return
+		}
+		command.Stdout = stdout
 	}
-	if cfg.Run.IgnoreStderr {
+	if cfg.Run.IgnoreStderr || cfg.Run.Pty.Enabled {
 		command.Stderr = nil
+	} else if cfg.Run.Stream {
+		stderrLines = newLineWriter("stderr", cfg.Run.MaxOutputBytes, processor, &cfg.Run.Multiline)
+		command.Stderr = stderrLines
 	} else {
-		command.Stderr = &stderr
+		if stderr, err = newOutputCapture("stderr", cfg.Run.MaxOutputBytes, spillDir, cfg.Run.ANSI); err != nil {
+			if stdout != nil {
+				stdout.Close()
+			}
+			log.Error().Err(err).Msgf("failed to capture output: %s", err.Error())
+			c.main.SetExitCode(errors.GeneralFailure)
+			return
+		}
+		command.Stderr = stderr
+	}
+	var combined *combinedCapture
+	if cfg.Run.Combined && !cfg.Run.Stream {
+		combined = newCombinedCapture(cfg.Run.MaxOutputBytes, cfg.Run.ANSI)
+		if stdout != nil {
+			command.Stdout = io.MultiWriter(stdout, combined.Writer("stdout"))
+		}
+		if stderr != nil {
+			command.Stderr = io.MultiWriter(stderr, combined.Writer("stderr"))
+		}
+	}
+
+	// run the command in a pseudo-terminal, which merges stderr into stdout
+	var pty *pseudoTerminal
+	var ptyOutput io.Writer = io.Discard
+	if cfg.Run.Pty.Enabled {
+		if pty, err = openPty(cfg.Run.Pty.Cols, cfg.Run.Pty.Rows); err != nil {
+			if stdout != nil {
+				stdout.Close()
+			}
+			log.Error().Err(err).Msgf("failed to open pseudo-terminal: %s", err.Error())
+			c.main.SetExitCode(errors.GeneralFailure)
+			return
+		}
+		if command.Stdout != nil {
+			ptyOutput = command.Stdout
+		}
+		pty.Attach(command)
+	} else if err = stdin.Attach(command); err != nil {
+		if stdout != nil {
+			stdout.Close()
+		}
+		if stderr != nil {
+			stderr.Close()
+		}
+		log.Error().Err(err).Msgf("failed to configure stdin: %s", err.Error())
+		c.main.SetExitCode(errors.GeneralFailure)
+		return
+	}
+
+	// run the command
+	if (cfg.Run.Timeout > 0 || cfg.Run.ForwardToGroup) && pty == nil {
+		setProcessGroup(command)
 	}
 	errorMessage := ""
+	errorType := ""
 	exitCode := errors.None
-	log.Info().Msgf("executing command: %s", strings.Join(args, " "))
-	if err := command.Run(); err != nil {
+	timedOut := false
+	var timeoutSignal syscall.Signal
+	var peakUsage *zerolog.Event
+	startLogger := launcher.Context().AddFields(log.With()).Logger()
+	if decision != nil {
+		startLogger = decision.AddFields(startLogger.With()).Logger()
+	}
+	if cfg.Run.Env.Record {
+		startLogger = startLogger.With().
+			Dict("env", env.Dict(&cfg.Run.Env)).
+			Logger()
+	}
+	if recorder := stdin.Recorder(); recorder != nil && recorder.Complete() {
+		startLogger = recorder.AddFields(startLogger.With()).Logger()
+	}
+	if cg != nil {
+		startLogger = cg.AddFields(startLogger.With()).Logger()
+	}
+	startLogger.Info().Msgf("executing command: %s", strings.Join(args, " "))
+	var reaper *reaper
+	if cfg.Run.Init {
+		r, err := newReaper()
+		if err != nil {
+			if pty != nil {
+				pty.Close()
+			}
+			log.Error().Err(err).Msgf("failed to enable init mode: %s", err.Error())
+			c.main.SetExitCode(errors.GeneralFailure)
+			return
+		}
+		reaper = r
+	}
+	forwarder := newSignalForwarder(cfg.Run.ForwardSignals)
+	oomKillsBefore, oomKillsKnown := readOOMKillCount()
+	startTime := time.Now()
+	err = launcher.Start(command)
+	if err != nil && pty != nil {
+		pty.Close()
+	}
+	if err != nil && decision != nil {
+		err = decision.RestorePath(err)
+	}
+	if err == nil {
+		if pty != nil {
+			pty.Start(ptyOutput)
+			pty.Feed(stdin.Reader())
+		} else {
+			stdin.Start()
+		}
+		// the command leads its process group, which includes the program when the helper stays behind as the init
+		// process of the sandbox
+		if cfg.Run.ForwardToGroup {
+			forwarder.Start(command.Process, true)
+		} else {
+			forwarder.Start(launcher.Target(command), false)
+		}
+		if reaper != nil {
+			reaper.Start(command.Process.Pid)
+		}
+		var sampler *sampler
+		if cfg.Run.SampleInterval > 0 {
+			sampler = startSampler(command.Process.Pid, cfg.Run.SampleInterval)
+		}
+		var watcher *timeoutWatcher
+		if cfg.Run.Timeout > 0 {
+			watcher = startTimeoutWatcher(command.Process, cfg.Run.Timeout, cfg.Run.KillAfter,
+				cfg.Run.TimeoutSignal)
+		}
+		err = command.Wait()
+		if pty != nil {
+			pty.Wait()
+		}
+		if watcher != nil {
+			timedOut, timeoutSignal = watcher.Stop()
+		}
+		if sampler != nil {
+			peakUsage = sampler.Stop()
+		}
+	}
+	forwarder.Stop()
+	if reaper != nil {
+		reaper.Stop()
+	}
+	filter := launcher.Seccomp()
+	if filter != nil {
+		filter.Stop()
+	}
+	finishTime := time.Now()
+	elapsed := finishTime.Sub(startTime)
+	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
 			errorMessage = exitErr.Error()
 		} else {
-			exitCode = 99
+			errorType = classifyStartError(command, err)
 			errorMessage = fmt.Sprintf("%s", err)
+			if errorType == startErrorNotFound {
+				exitCode = errors.CommandNotFound
+			} else {
+				exitCode = errors.CommandCannotExecute
+			}
+		}
+	}
+	status := getExitStatus(command.ProcessState, launcher.Status(), oomKillsBefore, oomKillsKnown)
+	commandCgroup := launcher.Cgroup()
+	if commandCgroup != nil && status.signaled && status.signal == syscall.SIGKILL && commandCgroup.OOMKills() > 0 {
+		status.oomKilled = true
+	}
+	if status.signaled {
+		exitCode = errors.SignalBase + int(status.signal)
+		errorMessage = status.String()
+	}
+	if timedOut {
+		exitCode = errors.CommandTimedOut
+	}
+	if stdoutLines != nil {
+		stdoutLines.Flush()
+	}
+	if stderrLines != nil {
+		stderrLines.Flush()
+	}
+	if stdout != nil {
+		if err := stdout.Close(); err != nil {
+			log.Warn().Err(err).Msgf("failed to close file for stdout output: %s", err.Error())
+		}
+	}
+	if stderr != nil {
+		if err := stderr.Close(); err != nil {
+			log.Warn().Err(err).Msgf("failed to close file for stderr output: %s", err.Error())
 		}
 	}
 
@@ -96,23 +720,115 @@ func (c *Command) run(cmd *cobra.Command, args []string) {
 	logger := log.With().
 		Int("exit_code", exitCode).
 		Logger()
+	if commandPath != "" {
+		logger = logger.With().
+			Str("command_path", commandPath).
+			Logger()
+	}
 	if errorMessage != "" {
-		logger = log.With().
+		logger = logger.With().
 			Str("error_message", errorMessage).
 			Logger()
 	}
-	if !cfg.Run.IgnoreStdout {
-		logger = log.With().
-			Str("stdout", stdout.String()).
+	if errorType != "" {
+		logger = logger.With().
+			Str("error_type", errorType).
+			Logger()
+	}
+	if settingErr, ok := err.(*settingError); ok {
+		logger = logger.With().
+			Str("setting", settingErr.setting).
+			Logger()
+	}
+	limit := limitExceeded(&cfg.Run.Limits, command.ProcessState, status)
+	if limit != "" {
+		logger = logger.With().
+			Str("limit_exceeded", limit).
 			Logger()
 	}
-	if !cfg.Run.IgnoreStderr {
-		logger = log.With().
-			Str("stderr", stderr.String()).
+	seccompKilled := filter != nil && filter.Killed(command.ProcessState, status)
+	if filter != nil && command.ProcessState != nil {
+		logger = filter.AddResultFields(logger.With(), seccompKilled).Logger()
+	}
+	if command.ProcessState != nil {
+		logger = logger.With().
+			Bool("signaled", status.signaled).
+			Logger()
+	}
+	if status.signaled {
+		logger = logger.With().
+			Str("signal", signals.Name(status.signal)).
+			Int("signal_number", int(status.signal)).
+			Bool("core_dumped", status.coreDumped).
+			Bool("oom_killed", status.oomKilled).
+			Logger()
+	}
+	if cfg.Run.Timeout > 0 {
+		logger = logger.With().
+			Bool("timed_out", timedOut).
+			Logger()
+	}
+	if timedOut {
+		logger = logger.With().
+			Str("timeout_signal", signals.Name(timeoutSignal)).
+			Int64("elapsed_ms", elapsed.Milliseconds()).
+			Logger()
+	}
+	if cfg.Run.Resources {
+		logger = logger.With().
+			Dict("resources", resourceUsage(command.ProcessState, startTime, finishTime)).
+			Logger()
+	}
+	if commandCgroup != nil {
+		logger = logger.With().
+			Dict("cgroup", commandCgroup.Stats()).
 			Logger()
 	}
+	if peakUsage != nil {
+		logger = logger.With().
+			Dict("peak_usage", peakUsage).
+			Logger()
+	}
+	if len(cfg.Run.Extract) > 0 && (stdout != nil || stderr != nil) {
+		extracted := map[string]interface{}{}
+		if stdout != nil {
+			stdout.ExtractFields(cfg.Run.Extract, extracted)
+		}
+		if stderr != nil {
+			stderr.ExtractFields(cfg.Run.Extract, extracted)
+		}
+		if len(extracted) > 0 {
+			logger = logger.With().
+				Interface("extracted", extracted).
+				Logger()
+		}
+	}
+	if stdout != nil {
+		logger = stdout.AddFields(logger.With()).Logger()
+	}
+	if stderr != nil {
+		logger = stderr.AddFields(logger.With()).Logger()
+	}
+	if combined != nil {
+		logger = combined.AddFields(logger.With()).Logger()
+	}
+	if recorder := stdin.Recorder(); recorder != nil && !recorder.Complete() {
+		logger = recorder.AddFields(logger.With()).Logger()
+	}
 
-	if exitCode != errors.None {
+	if errorType != "" {
+		logger.Error().Msgf("command failed to start: %s", errorMessage)
+	} else if timedOut {
+		logger.Warn().Msgf("command timed out after %s", cfg.Run.Timeout)
+	} else if limit != "" {
+		logger.Warn().Msgf("command exceeded its %s limit", strings.ReplaceAll(limit, "_", " "))
+	} else if seccompKilled {
+		logger.Warn().Msg("command was killed for making a system call denied by its seccomp filter")
+	} else if status.oomKilled {
+		logger.Warn().Msg("command was killed by the OOM killer")
+	} else if status.signaled {
+		logger.Warn().Msgf("command was terminated by %s", signals.Name(status.signal))
+	} else if exitCode != errors.None {
 		logger.Warn().Msgf("command exited with non-zero exit code %d", exitCode)
 	} else {
 		logger.Info().Msg("command completed successfully")
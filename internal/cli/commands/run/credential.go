@@ -0,0 +1,130 @@
+package run
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// credential contains the IDs of the user and groups the command is configured to run with.
+type credential struct {
+	// gid is the ID of the primary group, which is negative if it is not set.
+	gid int
+
+	// groups is the list of IDs of the supplementary groups, which is nil if they are not set.
+	groups []int
+
+	// uid is the ID of the user, which is negative if it is not set.
+	uid int
+}
+
+// lookupCredential resolves the names or IDs of the user and groups of the command into IDs.
+//
+// Unless given explicitly, the primary group and supplementary groups are those of the user. Numeric IDs which do not
+// belong to a known user or group are accepted as is since they are common in containers.
+func lookupCredential(cfg *config.ProcessConfig) (*credential, error) {
+	cred := &credential{
+		gid: -1,
+		uid: -1,
+	}
+
+	if cfg.User != "" {
+		u, err := lookupUser(cfg.User)
+		if err != nil {
+			return nil, &settingError{setting: "user", err: err}
+		}
+		if cred.uid, err = strconv.Atoi(u.Uid); err != nil {
+			return nil, &settingError{setting: "user", err: fmt.Errorf("user ID '%s' is not numeric", u.Uid)}
+		}
+		if u.Gid != "" {
+			if cred.gid, err = strconv.Atoi(u.Gid); err != nil {
+				return nil, &settingError{setting: "group", err: fmt.Errorf("group ID '%s' of user '%s' is not numeric",
+					u.Gid, cfg.User)}
+			}
+		}
+		if u.Username != "" && len(cfg.Groups) == 0 {
+			ids, err := u.GroupIds()
+			if err != nil {
+				return nil, &settingError{setting: "groups", err: fmt.Errorf(
+					"failed to determine supplementary groups of user '%s': %s", cfg.User, err.Error())}
+			}
+			cred.groups = []int{}
+			for _, id := range ids {
+				gid, err := strconv.Atoi(id)
+				if err != nil {
+					return nil, &settingError{setting: "groups", err: fmt.Errorf(
+						"group ID '%s' of user '%s' is not numeric", id, cfg.User)}
+				}
+				cred.groups = append(cred.groups, gid)
+			}
+		}
+	}
+
+	if cfg.Group != "" {
+		gid, err := lookupGroup(cfg.Group)
+		if err != nil {
+			return nil, &settingError{setting: "group", err: err}
+		}
+		cred.gid = gid
+	}
+
+	if len(cfg.Groups) > 0 {
+		cred.groups = make([]int, 0, len(cfg.Groups))
+		for _, name := range cfg.Groups {
+			gid, err := lookupGroup(name)
+			if err != nil {
+				return nil, &settingError{setting: "groups", err: err}
+			}
+			cred.groups = append(cred.groups, gid)
+		}
+	} else if cred.groups == nil && (cfg.User != "" || cfg.Group != "") {
+		cred.groups = []int{}
+	}
+	return cred, nil
+}
+
+// lookupUser looks up a user by name or ID.
+//
+// If the ID does not belong to a known user, a user with only the ID set is returned.
+func lookupUser(s string) (*user.User, error) {
+	if uid, err := strconv.Atoi(s); err == nil {
+		if uid < 0 {
+			return nil, fmt.Errorf("invalid user ID '%s': user ID must not be negative", s)
+		}
+		u, err := user.LookupId(s)
+		if _, ok := err.(user.UnknownUserIdError); ok {
+			return &user.User{Uid: s}, nil
+		}
+		return u, err
+	}
+	u, err := user.Lookup(s)
+	if _, ok := err.(user.UnknownUserError); ok {
+		return nil, fmt.Errorf("unknown user '%s'", s)
+	}
+	return u, err
+}
+
+// lookupGroup looks up the ID of a group by name or ID.
+//
+// Numeric IDs are returned as is.
+func lookupGroup(s string) (int, error) {
+	if gid, err := strconv.Atoi(s); err == nil {
+		if gid < 0 {
+			return 0, fmt.Errorf("invalid group ID '%s': group ID must not be negative", s)
+		}
+		return gid, nil
+	}
+	g, err := user.LookupGroup(s)
+	if _, ok := err.(user.UnknownGroupError); ok {
+		return 0, fmt.Errorf("unknown group '%s'", s)
+	} else if err != nil {
+		return 0, err
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group ID '%s' of group '%s' is not numeric", g.Gid, s)
+	}
+	return gid, nil
+}
@@ -0,0 +1,94 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"syscall"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// resolveCredential determines the effective user and groups of the command and verifies that json-exec has the
+// privileges required to switch to them.
+func resolveCredential(cfg *config.ProcessConfig, ctx *executionContext) error {
+	cred, err := lookupCredential(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx.uid = os.Geteuid()
+	ctx.gid = os.Getegid()
+	groups, err := os.Getgroups()
+	if err != nil {
+		return &settingError{setting: "groups", err: err}
+	}
+	ctx.groups = groups
+	ctx.credentialKnown = true
+
+	if cred.uid >= 0 && cred.uid != ctx.uid {
+		if !hasCapability(capSetUID) {
+			return &settingError{setting: "user", err: fmt.Errorf(
+				"running the command as user %d requires root privileges or the CAP_SETUID capability", cred.uid)}
+		}
+		ctx.uid = cred.uid
+		ctx.credentialChanged = true
+	}
+	if cred.gid >= 0 && cred.gid != ctx.gid {
+		if !hasCapability(capSetGID) {
+			return &settingError{setting: "group", err: fmt.Errorf(
+				"running the command as group %d requires root privileges or the CAP_SETGID capability", cred.gid)}
+		}
+		ctx.gid = cred.gid
+		ctx.credentialChanged = true
+	}
+	if cred.groups != nil && !sameGroups(cred.groups, ctx.groups) {
+		if !hasCapability(capSetGID) {
+			return &settingError{setting: "groups", err: fmt.Errorf(
+				"changing the supplementary groups of the command requires root privileges or the CAP_SETGID capability")}
+		}
+		ctx.groups = cred.groups
+		ctx.credentialChanged = true
+	}
+	return nil
+}
+
+// applyCredential configures the command to run with the user and groups of the execution context.
+func applyCredential(command *exec.Cmd, ctx *executionContext) {
+	if !ctx.credentialChanged {
+		return
+	}
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	groups := make([]uint32, 0, len(ctx.groups))
+	for _, gid := range ctx.groups {
+		groups = append(groups, uint32(gid))
+	}
+	command.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    uint32(ctx.uid),
+		Gid:    uint32(ctx.gid),
+		Groups: groups,
+	}
+}
+
+// sameGroups returns whether or not the two lists contain the same group IDs, ignoring order.
+func sameGroups(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]int{}, a...)
+	y := append([]int{}, b...)
+	sort.Ints(x)
+	sort.Ints(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestLookupCredential checks that the user and groups are resolved into IDs, with unknown numeric IDs accepted.
+func TestLookupCredential(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.ProcessConfig
+		uid     int
+		gid     int
+		groups  []int
+		setting string
+		err     string
+	}{
+		{name: "not set", uid: -1, gid: -1},
+		{name: "unknown user ID", cfg: config.ProcessConfig{User: "54321"}, uid: 54321, gid: -1, groups: []int{}},
+		{name: "group ID", cfg: config.ProcessConfig{Group: "4242"}, uid: -1, gid: 4242, groups: []int{}},
+		{name: "user and groups", cfg: config.ProcessConfig{User: "0", Group: "root", Groups: []string{"2", "1"}},
+			uid: 0, gid: 0, groups: []int{2, 1}},
+		{name: "negative user ID", cfg: config.ProcessConfig{User: "-1"}, setting: "user",
+			err: "user ID must not be negative"},
+		{name: "unknown user", cfg: config.ProcessConfig{User: "json-exec-no-such-user"}, setting: "user",
+			err: "unknown user 'json-exec-no-such-user'"},
+		{name: "unknown group", cfg: config.ProcessConfig{Group: "json-exec-no-such-group"}, setting: "group",
+			err: "unknown group 'json-exec-no-such-group'"},
+		{name: "negative group ID", cfg: config.ProcessConfig{Groups: []string{"1", "-5"}}, setting: "groups",
+			err: "group ID must not be negative"},
+	}
+	for _, tt := range tests {
+		cred, err := lookupCredential(&tt.cfg)
+		if tt.err != "" {
+			serr, ok := err.(*settingError)
+			if !ok || serr.setting != tt.setting || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: lookupCredential() = %v, expected %s error containing '%s'", tt.name, err, tt.setting,
+					tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err.Error())
+			continue
+		}
+		if cred.uid != tt.uid || cred.gid != tt.gid || !reflect.DeepEqual(cred.groups, tt.groups) {
+			t.Errorf("%s: lookupCredential() = %d, %d, %v, expected %d, %d, %v", tt.name, cred.uid, cred.gid,
+				cred.groups, tt.uid, tt.gid, tt.groups)
+		}
+	}
+}
+
+// TestSameGroups checks that lists of group IDs are compared without regard to order.
+func TestSameGroups(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want bool
+	}{
+		{a: nil, b: []int{}, want: true},
+		{a: []int{1, 2, 3}, b: []int{3, 1, 2}, want: true},
+		{a: []int{1, 2}, b: []int{1, 2, 3}, want: false},
+		{a: []int{1, 1, 2}, b: []int{1, 2, 2}, want: false},
+	}
+	for _, tt := range tests {
+		if got := sameGroups(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameGroups(%v, %v) = %t, expected %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
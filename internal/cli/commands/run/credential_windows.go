@@ -0,0 +1,20 @@
+package run
+
+import (
+	"fmt"
+	"os/exec"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// resolveCredential returns an error if the user or groups of the command are set since Windows does not support
+// running commands with different credentials.
+func resolveCredential(cfg *config.ProcessConfig, ctx *executionContext) error {
+	if cfg.User != "" || cfg.Group != "" || len(cfg.Groups) > 0 {
+		return &settingError{setting: "user", err: fmt.Errorf("running as a different user is not supported on Windows")}
+	}
+	return nil
+}
+
+// applyCredential does nothing on Windows.
+func applyCredential(command *exec.Cmd, ctx *executionContext) {}
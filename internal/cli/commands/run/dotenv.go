@@ -0,0 +1,156 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseDotenv parses the contents of a dotenv file, calling set for each variable in the order they appear.
+//
+// Each line contains a KEY=VALUE pair, optionally preceded by "export". Blank lines and lines starting with # are
+// ignored. Values may be unquoted, in which case surrounding whitespace and any comment starting with " #" are
+// removed; single-quoted, in which case they are taken literally; or double-quoted, in which case the escape sequences
+// \n, \r, \t, \", \\ and \$ are interpreted. Quoted values may span multiple lines. References to other variables using
+// $KEY or ${KEY} in unquoted and double-quoted values are expanded using get.
+func parseDotenv(data string, get func(string) (string, bool), set func(string, string)) error {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimSpace(line[len("export"):])
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return fmt.Errorf("line %d: expected KEY=VALUE", number)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !isEnvName(key) {
+			return fmt.Errorf("line %d: invalid variable name '%s'", number, key)
+		}
+		raw := strings.TrimSpace(line[eq+1:])
+
+		var value string
+		switch {
+		case strings.HasPrefix(raw, "'") || strings.HasPrefix(raw, `"`):
+			// gather lines until the closing quote is found
+			quote := raw[0]
+			raw = raw[1:]
+			end := findClosingQuote(raw, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				raw += "\n" + lines[i]
+				end = findClosingQuote(raw, quote)
+			}
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated quoted value", number)
+			}
+			if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return fmt.Errorf("line %d: unexpected characters after quoted value", number)
+			}
+			if quote == '\'' {
+				value = raw[:end]
+			} else {
+				value = interpolateEnv(raw[:end], true, get)
+			}
+		default:
+			if j := strings.Index(raw, " #"); j >= 0 {
+				raw = raw[:j]
+			} else if j := strings.Index(raw, "\t#"); j >= 0 {
+				raw = raw[:j]
+			}
+			value = interpolateEnv(strings.TrimSpace(raw), false, get)
+		}
+		set(key, value)
+	}
+	return nil
+}
+
+// findClosingQuote returns the index of the closing quote in the string or -1 if there is none.
+//
+// Double quotes may be escaped with a backslash.
+func findClosingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// interpolateEnv expands references to variables in the value and, if escapes is true, interprets escape sequences.
+//
+// References to variables which are not set expand to an empty string.
+func interpolateEnv(s string, escapes bool, get func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && escapes && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			value, _ := get(s[i+2 : i+2+end])
+			b.WriteString(value)
+			i += end + 2
+		case c == '$' && i+1 < len(s) && isEnvNameStart(s[i+1]):
+			end := i + 2
+			for end < len(s) && isEnvNameChar(s[end]) {
+				end++
+			}
+			value, _ := get(s[i+1 : end])
+			b.WriteString(value)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// isEnvName returns whether or not the string is a valid environment variable name.
+func isEnvName(s string) bool {
+	if s == "" || !isEnvNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isEnvNameChar(s[i]) && s[i] != '.' && s[i] != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// isEnvNameStart returns whether or not the character may start an environment variable name.
+func isEnvNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isEnvNameChar returns whether or not the character may appear in an environment variable reference.
+func isEnvNameChar(c byte) bool {
+	return isEnvNameStart(c) || (c >= '0' && c <= '9')
+}
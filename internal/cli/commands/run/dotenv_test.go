@@ -0,0 +1,57 @@
+package run
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseDotenv checks that variables are parsed from dotenv files in order, with quoting and interpolation.
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+		err  string
+	}{
+		{name: "empty", data: "", want: []string{}},
+		{name: "comments and blank lines", data: "# comment\n\n  \nA=1\n  # indented comment\r\nB=2\r\n",
+			want: []string{"A=1", "B=2"}},
+		{name: "export", data: "export A=1\nexport\tB = 2 \n", want: []string{"A=1", "B=2"}},
+		{name: "unquoted", data: "A=  some value  # comment\nB=a#b\nC=\nD=x\t# tab comment",
+			want: []string{"A=some value", "B=a#b", "C=", "D=x"}},
+		{name: "single quoted", data: `A='$HOME \n "raw"' # comment`, want: []string{`A=$HOME \n "raw"`}},
+		{name: "double quoted", data: `A="tab\tnew\nline \"q\" \\ \$HOME \x"`,
+			want: []string{"A=tab\tnew\nline \"q\" \\ $HOME \\x"}},
+		{name: "multiline", data: "A=\"first\nsecond\"\nB='x\n\ny'\nC=3",
+			want: []string{"A=first\nsecond", "B=x\n\ny", "C=3"}},
+		{name: "interpolation", data: "A=1\nB=${A}2\nC=\"$A-$B-${MISSING}-$\"\nD='$A'\nE=$HOME/bin\nF=${A",
+			want: []string{"A=1", "B=12", "C=1-12--$", "D=$A", "E=/home/user/bin", "F=${A"}},
+		{name: "overrides", data: "A=1\nA=2", want: []string{"A=1", "A=2"}},
+		{name: "names", data: "_a.b-c=1\nA9=2", want: []string{"_a.b-c=1", "A9=2"}},
+		{name: "missing equals", data: "A=1\nB", err: "line 2: expected KEY=VALUE"},
+		{name: "invalid name", data: "9A=1", err: "line 1: invalid variable name '9A'"},
+		{name: "empty name", data: "=1", err: "line 1: invalid variable name ''"},
+		{name: "unterminated quote", data: "A=1\nB=\"open\nstill open", err: "line 2: unterminated quoted value"},
+		{name: "characters after quote", data: "A='x' y", err: "line 1: unexpected characters after quoted value"},
+	}
+	for _, tt := range tests {
+		values := map[string]string{"HOME": "/home/user"}
+		got := []string{}
+		err := parseDotenv(tt.data, func(name string) (string, bool) {
+			value, ok := values[name]
+			return value, ok
+		}, func(name, value string) {
+			values[name] = value
+			got = append(got, name+"="+value)
+		})
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", tt.name, err.Error())
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: parseDotenv() = %v, expected error containing '%s'", tt.name, err, tt.err)
+		case tt.err == "" && !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%s: parseDotenv() set %q, expected %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// redacted replaces the values of variables which are not recorded.
+const redacted = "[REDACTED]"
+
+// environment is the set of variables passed to the command, which keeps track of the order they were set in.
+type environment struct {
+	// unexported members
+	names  []string
+	values map[string]string
+}
+
+// buildEnvironment builds the environment of the command.
+//
+// Variables are inherited from json-exec according to the options and are then set from each of the files followed
+// by the variables given explicitly, with later values overriding earlier ones.
+func buildEnvironment(cfg *config.EnvConfig) (*environment, error) {
+	env := &environment{
+		values: map[string]string{},
+	}
+	for _, v := range os.Environ() {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) == 2 && kv[0] != "" && cfg.Inherits(kv[0]) {
+			env.Set(kv[0], kv[1])
+		}
+	}
+	for _, file := range cfg.Files {
+		if err := env.load(file); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range cfg.Vars {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) == 2 {
+			env.Set(kv[0], kv[1])
+		} else if value, ok := os.LookupEnv(kv[0]); ok {
+			env.Set(kv[0], value)
+		}
+	}
+	return env, nil
+}
+
+// Get returns the value of the variable, if it is set.
+func (e *environment) Get(name string) (string, bool) {
+	value, ok := e.values[name]
+	return value, ok
+}
+
+// Set sets the value of the variable.
+func (e *environment) Set(name, value string) {
+	if _, ok := e.values[name]; !ok {
+		e.names = append(e.names, name)
+	}
+	e.values[name] = value
+}
+
+// List returns the variables in KEY=VALUE format.
+func (e *environment) List() []string {
+	list := make([]string, 0, len(e.names))
+	for _, name := range e.names {
+		list = append(list, name+"="+e.values[name])
+	}
+	return list
+}
+
+// Dict returns the variables as a dictionary for logging, sorted by name, with the values of variables which are not
+// recorded redacted.
+func (e *environment) Dict(cfg *config.EnvConfig) *zerolog.Event {
+	names := append([]string(nil), e.names...)
+	sort.Strings(names)
+	dict := zerolog.Dict()
+	for _, name := range names {
+		if cfg.RecordsValue(name) {
+			dict = dict.Str(name, e.values[name])
+		} else {
+			dict = dict.Str(name, redacted)
+		}
+	}
+	return dict
+}
+
+// load sets the variables in the dotenv file.
+//
+// References to other variables in the file are expanded using the variables set so far.
+func (e *environment) load(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read environment file: %s", err.Error())
+	}
+	if err := parseDotenv(string(data), e.Get, e.Set); err != nil {
+		return fmt.Errorf("failed to parse environment file '%s': %s", file, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestBuildEnvironment checks that variables are inherited according to the options and then set from the files
+// followed by the variables given explicitly.
+func TestBuildEnvironment(t *testing.T) {
+	os.Setenv("JSON_EXEC_TEST_A", "inherited")
+	os.Setenv("JSON_EXEC_TEST_SECRET", "hidden")
+	defer os.Unsetenv("JSON_EXEC_TEST_A")
+	defer os.Unsetenv("JSON_EXEC_TEST_SECRET")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.env")
+	if err := os.WriteFile(file, []byte("B=from file\nC=${JSON_EXEC_TEST_A}-${B}\n"), 0644); err != nil {
+		t.Fatalf("failed to create environment file: %s", err.Error())
+	}
+	invalid := filepath.Join(dir, "invalid.env")
+	if err := os.WriteFile(invalid, []byte("not a variable\n"), 0644); err != nil {
+		t.Fatalf("failed to create environment file: %s", err.Error())
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.EnvConfig
+		want []string
+		err  string
+	}{
+		{name: "clear", cfg: config.EnvConfig{Clear: true, Vars: []string{"X=1", "JSON_EXEC_TEST_A"}},
+			want: []string{"X=1", "JSON_EXEC_TEST_A=inherited"}},
+		{name: "allow", cfg: config.EnvConfig{Allow: []string{"JSON_EXEC_TEST_*"}, Deny: []string{"*SECRET"}},
+			want: []string{"JSON_EXEC_TEST_A=inherited"}},
+		{name: "files and vars", cfg: config.EnvConfig{Allow: []string{"JSON_EXEC_TEST_A"}, Files: []string{file},
+			Vars: []string{"B=from vars", "JSON_EXEC_TEST_MISSING"}},
+			want: []string{"JSON_EXEC_TEST_A=inherited", "B=from vars", "C=inherited-from file"}},
+		{name: "missing file", cfg: config.EnvConfig{Clear: true, Files: []string{file + ".missing"}},
+			err: "failed to read environment file"},
+		{name: "invalid file", cfg: config.EnvConfig{Clear: true, Files: []string{invalid}},
+			err: "failed to parse environment file '" + invalid + "': line 1"},
+	}
+	for _, tt := range tests {
+		env, err := buildEnvironment(&tt.cfg)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", tt.name, err.Error())
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: buildEnvironment() = %v, expected error containing '%s'", tt.name, err, tt.err)
+		case tt.err == "":
+			if got := env.List(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: List() = %q, expected %q", tt.name, got, tt.want)
+			}
+		}
+	}
+}
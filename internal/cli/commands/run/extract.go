@@ -0,0 +1,68 @@
+package run
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/json-exec/internal/grok"
+)
+
+// extractFields applies the extraction rules to a line of output from the given stream.
+//
+// Extracted fields are added to the given fields, overwriting any existing fields with the same name, and the
+// resulting fields are returned. If fields is nil and no fields are extracted, nil is returned.
+func extractFields(rules []config.ExtractRule, stream, text string,
+	fields map[string]interface{}) map[string]interface{} {
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.AppliesTo(stream) {
+			continue
+		}
+		match := rule.Regexp.FindStringSubmatchIndex(text)
+		if match == nil {
+			continue
+		}
+		for group, name := range rule.Regexp.SubexpNames() {
+			if name == "" || match[2*group] < 0 {
+				continue // unnamed group or group did not participate in the match
+			}
+			if fields == nil {
+				fields = map[string]interface{}{}
+			}
+			fields[name] = convertValue(text[match[2*group]:match[2*group+1]], rule.TypeOf(name))
+		}
+	}
+	return fields
+}
+
+// convertValue converts an extracted value to the given type.
+//
+// Durations are converted to a floating point number of milliseconds. If the value cannot be converted, it is
+// returned as a string.
+func convertValue(value, t string) interface{} {
+	switch t {
+	case grok.TypeBool:
+		switch strings.ToLower(value) {
+		case "1", "t", "true", "y", "yes", "on":
+			return true
+		case "0", "f", "false", "n", "no", "off":
+			return false
+		}
+	case grok.TypeDuration:
+		if d, err := time.ParseDuration(value); err == nil {
+			return float64(d) / float64(time.Millisecond)
+		}
+	case grok.TypeFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case grok.TypeInt:
+		if i, err := strconv.ParseInt(strings.TrimPrefix(value, "+"), 10, 64); err == nil {
+			return i
+		}
+	}
+	return value
+}
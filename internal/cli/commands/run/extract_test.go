@@ -0,0 +1,71 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/json-exec/internal/grok"
+	"gopkg.in/yaml.v3"
+)
+
+// TestConvertValue checks that extracted values are converted to their types, falling back to strings.
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		value string
+		t     string
+		want  interface{}
+	}{
+		{value: "yes", t: grok.TypeBool, want: true},
+		{value: "OFF", t: grok.TypeBool, want: false},
+		{value: "maybe", t: grok.TypeBool, want: "maybe"},
+		{value: "1.5s", t: grok.TypeDuration, want: 1500.0},
+		{value: "250us", t: grok.TypeDuration, want: 0.25},
+		{value: "soon", t: grok.TypeDuration, want: "soon"},
+		{value: "2.5", t: grok.TypeFloat, want: 2.5},
+		{value: "1e3", t: grok.TypeFloat, want: 1000.0},
+		{value: "x", t: grok.TypeFloat, want: "x"},
+		{value: "+42", t: grok.TypeInt, want: int64(42)},
+		{value: "-7", t: grok.TypeInt, want: int64(-7)},
+		{value: "4.2", t: grok.TypeInt, want: "4.2"},
+		{value: "42", t: grok.TypeString, want: "42"},
+	}
+	for _, tt := range tests {
+		if got := convertValue(tt.value, tt.t); got != tt.want {
+			t.Errorf("convertValue(%q, %s) = %#v, expected %#v", tt.value, tt.t, got, tt.want)
+		}
+	}
+}
+
+// TestExtractFields checks that the rules which apply to the stream add typed fields, with later rules taking
+// precedence.
+func TestExtractFields(t *testing.T) {
+	var cfg config.RunConfig
+	data := `
+extract:
+  - pattern: 'status=%{INT:status:int}'
+  - pattern: 'took (?P<elapsed>\S+)'
+    types: {Elapsed: duration}
+    streams: [stderr]
+  - pattern: 'status=(?P<status>\d+)(?P<unused>x)?'
+    streams: [STDOUT]
+`
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	tests := []struct {
+		stream string
+		text   string
+		want   map[string]interface{}
+	}{
+		{stream: config.StreamStderr, text: "status=200 took 2ms",
+			want: map[string]interface{}{"status": int64(200), "elapsed": 2.0}},
+		{stream: config.StreamStdout, text: "status=200 took 2ms", want: map[string]interface{}{"status": "200"}},
+		{stream: config.StreamStdout, text: "nothing to see", want: nil},
+	}
+	for _, tt := range tests {
+		if got := extractFields(cfg.Extract, tt.stream, tt.text, nil); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractFields(%s, %q) = %v, expected %v", tt.stream, tt.text, got, tt.want)
+		}
+	}
+}
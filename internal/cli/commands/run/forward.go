@@ -0,0 +1,84 @@
+package run
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"go.sophtrust.dev/json-exec/internal/signals"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// signalForwarder relays signals received by the application to the running command.
+type signalForwarder struct {
+	// unexported members
+	done     chan struct{}
+	received chan os.Signal
+	wg       sync.WaitGroup
+}
+
+// newSignalForwarder creates a new signalForwarder object and immediately begins catching the given signals.
+//
+// Signals are caught before the command is started so that a signal arriving while the command is starting does
+// not terminate the application before the result can be reported. Caught signals are queued until Start() is
+// called.
+func newSignalForwarder(sigs []syscall.Signal) *signalForwarder {
+	f := &signalForwarder{
+		done:     make(chan struct{}),
+		received: make(chan os.Signal, 16),
+	}
+	if len(sigs) > 0 {
+		notify := make([]os.Signal, 0, len(sigs))
+		for _, sig := range sigs {
+			notify = append(notify, sig)
+		}
+		signal.Notify(f.received, notify...)
+	}
+	return f
+}
+
+// Start begins forwarding caught signals to the process or, if toGroup is true, to its entire process group.
+func (f *signalForwarder) Start(process *os.Process, toGroup bool) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			select {
+			case <-f.done:
+				return
+			case s := <-f.received:
+				sig, ok := s.(syscall.Signal)
+				if !ok {
+					continue
+				}
+				var err error
+				if toGroup {
+					err = signalProcessGroup(process, sig)
+				} else {
+					err = process.Signal(sig)
+				}
+				if err != nil {
+					log.Error().
+						Err(err).
+						Str("signal", signals.Name(sig)).
+						Msgf("failed to forward %s to command: %s", signals.Name(sig), err.Error())
+				} else {
+					log.Info().
+						Str("signal", signals.Name(sig)).
+						Bool("process_group", toGroup).
+						Msgf("forwarded %s to command", signals.Name(sig))
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops catching signals and waits for any in-progress forwarding to finish.
+//
+// Once stopped, the signals are restored to their default behavior.
+func (f *signalForwarder) Stop() {
+	signal.Stop(f.received)
+	close(f.done)
+	f.wg.Wait()
+}
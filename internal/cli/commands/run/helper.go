@@ -0,0 +1,305 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// HelperArg is the first argument given to json-exec when it is executed as a helper.
+//
+// Some settings, such as resource limits, apply to an entire process and can only be applied to the command between
+// the time it is forked and the time it executes the actual program. Since Go does not allow running code at that
+// point, json-exec executes itself as a helper in place of the command. The helper applies the settings to itself
+// and then replaces itself with the actual program, keeping the same process ID.
+const HelperArg = "__json-exec-helper"
+
+// helperSpec contains the settings the helper applies before executing the program.
+type helperSpec struct {
+	// Credential contains the user and groups the helper switches to after applying the other settings, if any.
+	Credential *helperCredential `json:"credential,omitempty"`
+
+	// HoldFD is the file descriptor from which the helper reads before applying any settings, if any.
+	//
+	// The helper waits until the other end of the pipe is closed so that json-exec can prepare the process, such as
+	// moving it into a cgroup, before the program is executed.
+	HoldFD int `json:"hold_fd,omitempty"`
+
+	// Landlock contains the files the program may access, if restricted.
+	Landlock *helperLandlock `json:"landlock,omitempty"`
+
+	// ReportFD is the file descriptor to which the helper writes a report if it fails.
+	ReportFD int `json:"report_fd"`
+
+	// Rlimits is the list of resource limits to set.
+	Rlimits []helperRlimit `json:"rlimits,omitempty"`
+
+	// Sandbox contains the settings applied inside the namespaces of the sandbox, if any.
+	Sandbox *helperSandbox `json:"sandbox,omitempty"`
+
+	// Seccomp contains the seccomp filter installed right before executing the program, if any.
+	Seccomp *helperSeccomp `json:"seccomp,omitempty"`
+
+	// StatusFD is the file descriptor to which the helper writes how the program terminated when it stays behind as
+	// the init process of the sandbox, if any.
+	StatusFD int `json:"status_fd,omitempty"`
+}
+
+// helperCredential contains the user and groups the helper switches to.
+type helperCredential struct {
+	// GID is the ID of the primary group.
+	GID int `json:"gid"`
+
+	// Groups is the list of IDs of the supplementary groups.
+	Groups []int `json:"groups"`
+
+	// UID is the ID of the user.
+	UID int `json:"uid"`
+}
+
+// helperLandlock contains the files the program may access.
+type helperLandlock struct {
+	// ABI is the version of the Landlock ABI supported by the kernel.
+	ABI int `json:"abi"`
+
+	// ReadOnly is the list of paths beneath which files may be read and executed.
+	ReadOnly []string `json:"read_only,omitempty"`
+
+	// ReadWrite is the list of paths beneath which files may be read, written, executed, created and removed.
+	ReadWrite []string `json:"read_write,omitempty"`
+}
+
+// helperSandbox contains the settings the helper applies inside the namespaces of the sandbox.
+type helperSandbox struct {
+	// Binds is the list of read-only bind mounts.
+	Binds []helperBind `json:"binds,omitempty"`
+
+	// Loopback indicates whether or not to bring up the loopback interface of a new network namespace.
+	Loopback bool `json:"loopback"`
+
+	// MountProc indicates whether or not to mount a new /proc for a new PID namespace.
+	MountProc bool `json:"mount_proc"`
+
+	// PrivateTmp indicates whether or not to mount an empty tmpfs on /tmp.
+	PrivateTmp bool `json:"private_tmp"`
+}
+
+// helperBind is a read-only bind mount made by the helper.
+type helperBind struct {
+	// Source is the path on the host.
+	Source string `json:"source"`
+
+	// Target is the path in the sandbox.
+	Target string `json:"target"`
+}
+
+// helperSeccomp contains the seccomp filter installed by the helper.
+type helperSeccomp struct {
+	// Filter is the BPF program of the filter.
+	Filter []helperInstruction `json:"filter"`
+
+	// ListenerFD is the file descriptor of the socket over which the helper sends the listener of the filter, if any.
+	//
+	// When set, system calls which are not allowed are reported to json-exec through the listener, which fails them
+	// with EPERM after recording them.
+	ListenerFD int `json:"listener_fd,omitempty"`
+}
+
+// helperInstruction is a BPF instruction of a seccomp filter.
+type helperInstruction struct {
+	// Code is the operation code.
+	Code uint16 `json:"code"`
+
+	// Jf is the offset of the next instruction when a condition is false.
+	Jf uint8 `json:"jf"`
+
+	// Jt is the offset of the next instruction when a condition is true.
+	Jt uint8 `json:"jt"`
+
+	// K is the constant operand.
+	K uint32 `json:"k"`
+}
+
+// helperRlimit contains a resource limit set by the helper.
+type helperRlimit struct {
+	// Cur is the soft limit.
+	Cur uint64 `json:"cur"`
+
+	// Max is the hard limit.
+	Max uint64 `json:"max"`
+
+	// Name is the name of the limit as used in log messages.
+	Name string `json:"name"`
+
+	// Resource is the resource to limit.
+	Resource int `json:"resource"`
+}
+
+// helperReport contains the reason the helper failed to execute the program.
+type helperReport struct {
+	// Errno is the system error number, if any.
+	Errno int `json:"errno"`
+
+	// Message is the error message.
+	Message string `json:"message"`
+
+	// Setting is the name of the setting which could not be applied.
+	//
+	// If empty, the program itself could not be executed.
+	Setting string `json:"setting"`
+}
+
+// helperStatus contains the signal which terminated the program when the helper stays behind as the init process of
+// the sandbox.
+//
+// The init process of a PID namespace cannot be terminated by a signal sent from within the namespace, so the helper
+// exits with the exit code a shell would report and writes the signal to the status file descriptor instead.
+type helperStatus struct {
+	// CoreDumped indicates whether or not the program produced a core dump.
+	CoreDumped bool `json:"core_dumped"`
+
+	// Signal is the number of the signal.
+	Signal int `json:"signal"`
+}
+
+// helperProcess tracks the helper executed in place of the command.
+type helperProcess struct {
+	// unexported members
+	holdReader   *os.File
+	holdWriter   *os.File
+	path         string
+	reader       *os.File
+	statusReader *os.File
+	statusWriter *os.File
+	writer       *os.File
+}
+
+// wrapWithHelper modifies the command so that the helper is executed in its place with the given settings.
+//
+// If hold is true, the helper waits until it is released before applying the settings.
+func wrapWithHelper(command *exec.Cmd, spec helperSpec, hold bool) (*helperProcess, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate json-exec executable: %s", err.Error())
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helper pipe: %s", err.Error())
+	}
+	h := &helperProcess{
+		path:   command.Path,
+		reader: reader,
+		writer: writer,
+	}
+	spec.ReportFD = 3 + len(command.ExtraFiles)
+	command.ExtraFiles = append(command.ExtraFiles, writer)
+	if hold {
+		if h.holdReader, h.holdWriter, err = os.Pipe(); err != nil {
+			h.close()
+			reader.Close()
+			return nil, fmt.Errorf("failed to create helper pipe: %s", err.Error())
+		}
+		spec.HoldFD = 3 + len(command.ExtraFiles)
+		command.ExtraFiles = append(command.ExtraFiles, h.holdReader)
+	}
+	if spec.Sandbox != nil {
+		if h.statusReader, h.statusWriter, err = os.Pipe(); err != nil {
+			h.Release()
+			h.close()
+			reader.Close()
+			return nil, fmt.Errorf("failed to create helper pipe: %s", err.Error())
+		}
+		spec.StatusFD = 3 + len(command.ExtraFiles)
+		command.ExtraFiles = append(command.ExtraFiles, h.statusWriter)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil { // should never happen
+		h.Release()
+		h.close()
+		reader.Close()
+		h.Status()
+		return nil, err
+	}
+	command.Args = append([]string{self, HelperArg, string(data), command.Path}, command.Args...)
+	command.Path = self
+	return h, nil
+}
+
+// Release allows a held helper to continue.
+func (h *helperProcess) Release() {
+	if h.holdWriter != nil {
+		h.holdWriter.Close()
+		h.holdWriter = nil
+	}
+}
+
+// Wait waits until the helper has either executed the program or failed to do so.
+//
+// startErr is the error returned when starting the helper. If the helper failed, the error it reported is returned
+// and the helper process is reaped.
+func (h *helperProcess) Wait(command *exec.Cmd, startErr error) error {
+	h.Release()
+	h.close()
+	if startErr != nil {
+		h.reader.Close()
+		h.Status()
+		return startErr
+	}
+
+	// the pipe is closed without any data once the program is executed successfully
+	data, err := io.ReadAll(h.reader)
+	h.reader.Close()
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	command.Process.Wait()
+	h.Status()
+
+	var report helperReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("helper failed: %s", string(data))
+	}
+	var cause error = fmt.Errorf("%s", report.Message)
+	if report.Errno != 0 {
+		cause = syscall.Errno(report.Errno)
+	}
+	if report.Setting != "" {
+		return &settingError{setting: report.Setting, err: cause}
+	}
+	return &os.PathError{Op: "fork/exec", Path: h.path, Err: cause}
+}
+
+// Status returns the signal which terminated the program when the helper stayed behind as the init process of the
+// sandbox, which is nil if the program was not terminated by a signal.
+//
+// This function must only be called once the helper has exited.
+func (h *helperProcess) Status() *helperStatus {
+	if h.statusReader == nil {
+		return nil
+	}
+	data, err := io.ReadAll(h.statusReader)
+	h.statusReader.Close()
+	h.statusReader = nil
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var status helperStatus
+	if err := json.Unmarshal(data, &status); err != nil || status.Signal == 0 {
+		return nil
+	}
+	return &status
+}
+
+// close closes the ends of the pipes which belong to the helper.
+func (h *helperProcess) close() {
+	h.writer.Close()
+	if h.holdReader != nil {
+		h.holdReader.Close()
+	}
+	if h.statusWriter != nil {
+		h.statusWriter.Close()
+	}
+}
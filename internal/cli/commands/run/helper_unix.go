@@ -0,0 +1,132 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+
+	"go.sophtrust.dev/json-exec/internal/errors"
+)
+
+// accessExecute checks whether or not a file may be executed, which is missing from the syscall package.
+const accessExecute = 0x1
+
+// RunHelper applies the settings given on the command line to the current process and then executes the program.
+//
+// This function never returns. If the settings cannot be applied or the program cannot be executed, the reason is
+// written to the report file descriptor and the helper exits.
+func RunHelper(args []string) {
+	if len(args) < 3 {
+		fmt.Fprintf(os.Stderr, "%s must only be used by json-exec itself\n", HelperArg)
+		os.Exit(errors.Usage)
+	}
+	var spec helperSpec
+	if err := json.Unmarshal([]byte(args[0]), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid helper settings: %s\n", err.Error())
+		os.Exit(errors.Usage)
+	}
+	syscall.CloseOnExec(spec.ReportFD)
+	report := os.NewFile(uintptr(spec.ReportFD), "report")
+	if spec.Seccomp != nil && spec.Seccomp.ListenerFD > 0 {
+		syscall.CloseOnExec(spec.Seccomp.ListenerFD)
+	}
+	if spec.StatusFD > 0 {
+		syscall.CloseOnExec(spec.StatusFD)
+	}
+
+	// no new privileges, Landlock rules and seccomp filters only apply to the current thread and are kept by the
+	// program, so the program must be executed from the same thread
+	runtime.LockOSThread()
+
+	fail := func(setting string, err error) {
+		r := helperReport{
+			Message: err.Error(),
+			Setting: setting,
+		}
+		if errno, ok := err.(syscall.Errno); ok {
+			r.Errno = int(errno)
+		}
+		data, _ := json.Marshal(r)
+		report.Write(data)
+		report.Close()
+		os.Exit(errors.CommandCannotExecute)
+	}
+
+	if spec.HoldFD > 0 {
+		hold := os.NewFile(uintptr(spec.HoldFD), "hold")
+		hold.Read(make([]byte, 1))
+		hold.Close()
+	}
+
+	if spec.Sandbox != nil {
+		if setting, err := setupSandbox(spec.Sandbox); err != nil {
+			fail(setting, err)
+		}
+		fail("sandbox init", runSandboxInit(&spec, report, args))
+	}
+
+	// the limits are only lowered once the helper has nothing left to do which they could make fail, but raising a
+	// hard limit requires privileges which may be dropped along with the credentials
+	for _, limit := range spec.Rlimits {
+		if err := raiseRlimit(limit); err != nil {
+			fail(limit.Name+" limit", err)
+		}
+	}
+	if spec.Landlock != nil || spec.Seccomp != nil {
+		if err := setNoNewPrivileges(); err != nil {
+			fail("no new privileges", err)
+		}
+	}
+	if spec.Landlock != nil {
+		if setting, err := applyLandlock(spec.Landlock); err != nil {
+			fail(setting, err)
+		}
+	}
+	if spec.Credential != nil {
+		if err := syscall.Setgroups(spec.Credential.Groups); err != nil {
+			fail("groups", err)
+		}
+		if err := syscall.Setgid(spec.Credential.GID); err != nil {
+			fail("group", err)
+		}
+		if err := syscall.Setuid(spec.Credential.UID); err != nil {
+			fail("user", err)
+		}
+	}
+	for _, limit := range spec.Rlimits {
+		if err := applyRlimit(limit); err != nil {
+			fail(limit.Name+" limit", err)
+		}
+	}
+	if spec.Seccomp == nil {
+		fail("", syscall.Exec(args[1], args[2:], os.Environ()))
+	}
+
+	// the filter may deny the system calls needed to write the report, so the reasons the program cannot be executed
+	// are checked beforehand, leaving only rare failures such as a missing interpreter unreported
+	if err := checkExecutable(args[1]); err != nil {
+		fail("", err)
+	}
+
+	fail(execWithSeccomp(spec.Seccomp, args[1], args[2:], os.Environ()))
+}
+
+// checkExecutable returns the error executing the program at the given path would most likely fail with.
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if pathErr, ok := err.(*os.PathError); ok {
+			return pathErr.Err
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return syscall.EACCES
+	}
+	return syscall.Access(path, accessExecute)
+}
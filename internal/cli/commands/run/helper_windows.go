@@ -0,0 +1,14 @@
+package run
+
+import (
+	"fmt"
+	"os"
+
+	"go.sophtrust.dev/json-exec/internal/errors"
+)
+
+// RunHelper always exits since the helper is not used on Windows.
+func RunHelper(args []string) {
+	fmt.Fprintf(os.Stderr, "%s is not supported on Windows\n", HelperArg)
+	os.Exit(errors.Usage)
+}
@@ -0,0 +1,168 @@
+package run
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// Landlock system calls and options, which are missing from the syscall package.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+)
+
+// Landlock filesystem access rights.
+const (
+	landlockAccessExecute = 1 << iota
+	landlockAccessWriteFile
+	landlockAccessReadFile
+	landlockAccessReadDir
+	landlockAccessRemoveDir
+	landlockAccessRemoveFile
+	landlockAccessMakeChar
+	landlockAccessMakeDir
+	landlockAccessMakeReg
+	landlockAccessMakeSock
+	landlockAccessMakeFifo
+	landlockAccessMakeBlock
+	landlockAccessMakeSym
+	landlockAccessRefer
+	landlockAccessTruncate
+	landlockAccessIoctlDev
+)
+
+// access rights granted beneath read-only paths
+const landlockAccessReadOnly = landlockAccessExecute | landlockAccessReadFile | landlockAccessReadDir
+
+// access rights which apply to files other than directories
+const landlockAccessFile = landlockAccessExecute | landlockAccessWriteFile | landlockAccessReadFile |
+	landlockAccessTruncate | landlockAccessIoctlDev
+
+// landlockRulesetAttr is the landlock_ruleset_attr structure.
+type landlockRulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// landlockPathBeneathAttr is the landlock_path_beneath_attr structure.
+//
+// The kernel only reads the first 12 bytes since the structure is packed.
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFD      int32
+}
+
+// landlock restricts the files the command may access.
+//
+// Landlock rules can only be applied by a process to itself, so the helper applies them before executing the program.
+// Any access to a file which is not beneath one of the allowed paths fails with EACCES.
+type landlock struct {
+	// unexported members
+	abi int
+	cfg *config.LandlockConfig
+}
+
+// newLandlock checks that Landlock is supported by the kernel.
+func newLandlock(cfg *config.LandlockConfig) (*landlock, error) {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return nil, &settingError{setting: "Landlock", err: fmt.Errorf("Landlock is not enabled in the kernel: %s",
+			errno.Error())}
+	}
+	return &landlock{
+		abi: int(abi),
+		cfg: cfg,
+	}, nil
+}
+
+// HelperSpec returns the files the helper allows the program to access.
+func (l *landlock) HelperSpec() *helperLandlock {
+	return &helperLandlock{
+		ABI:       l.abi,
+		ReadOnly:  l.cfg.ReadOnly,
+		ReadWrite: l.cfg.ReadWrite,
+	}
+}
+
+// AddFields adds the files the command may access to the given logging context.
+func (l *landlock) AddFields(ctx zerolog.Context) zerolog.Context {
+	return ctx.Dict("landlock", zerolog.Dict().
+		Int("abi", l.abi).
+		Strs("read_only", l.cfg.ReadOnly).
+		Strs("read_write", l.cfg.ReadWrite))
+}
+
+// applyLandlock restricts the files the current thread and any program it executes may access.
+//
+// This function is called by the helper after no new privileges has been set. The name of the setting which failed
+// is returned along with the error.
+func applyLandlock(spec *helperLandlock) (string, error) {
+	// only handle the access rights known to the kernel so that newer rights are not silently allowed
+	var handled uint64
+	switch {
+	case spec.ABI >= 5:
+		handled = landlockAccessIoctlDev<<1 - 1
+	case spec.ABI >= 3:
+		handled = landlockAccessTruncate<<1 - 1
+	case spec.ABI == 2:
+		handled = landlockAccessRefer<<1 - 1
+	default:
+		handled = landlockAccessMakeSym<<1 - 1
+	}
+	attr := landlockRulesetAttr{handledAccessFS: handled}
+	ruleset, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)),
+		unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return "Landlock", errno
+	}
+	defer syscall.Close(int(ruleset))
+
+	for _, path := range spec.ReadOnly {
+		if err := addLandlockRule(int(ruleset), path, landlockAccessReadOnly&handled); err != nil {
+			return "Landlock path " + path, err
+		}
+	}
+	for _, path := range spec.ReadWrite {
+		if err := addLandlockRule(int(ruleset), path, handled); err != nil {
+			return "Landlock path " + path, err
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(sysLandlockRestrictSelf, ruleset, 0, 0); errno != 0 {
+		return "Landlock", errno
+	}
+	return "", nil
+}
+
+// addLandlockRule allows the given access to the files beneath the path.
+func addLandlockRule(ruleset int, path string, access uint64) error {
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	// rights which only apply to directories cannot be granted on other files
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return err
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= landlockAccessFile
+	}
+	attr := landlockPathBeneathAttr{
+		allowedAccess: access,
+		parentFD:      int32(fd),
+	}
+	if _, _, errno := syscall.RawSyscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
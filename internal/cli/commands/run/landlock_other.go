@@ -0,0 +1,34 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// landlock is not supported on this platform.
+type landlock struct{}
+
+// newLandlock always returns an error since Landlock is only supported on Linux.
+func newLandlock(cfg *config.LandlockConfig) (*landlock, error) {
+	return nil, &settingError{setting: "Landlock", err: fmt.Errorf("Landlock is only supported on Linux")}
+}
+
+// HelperSpec always returns nil.
+func (l *landlock) HelperSpec() *helperLandlock {
+	return nil
+}
+
+// AddFields does nothing.
+func (l *landlock) AddFields(ctx zerolog.Context) zerolog.Context {
+	return ctx
+}
+
+// applyLandlock always returns an error since Landlock is only supported on Linux.
+func applyLandlock(spec *helperLandlock) (string, error) {
+	return "Landlock", fmt.Errorf("Landlock is only supported on Linux")
+}
@@ -0,0 +1,333 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// settingWorkingDirectory is the name of the working directory setting, which fails the same way as when the command
+// cannot be started in its working directory.
+const settingWorkingDirectory = "working directory"
+
+// settingError is returned when one of the settings of the execution context cannot be applied.
+type settingError struct {
+	setting string
+	err     error
+}
+
+// Error returns the error message.
+func (e *settingError) Error() string {
+	return fmt.Sprintf("failed to set %s: %s", e.setting, e.err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *settingError) Unwrap() error {
+	return e.err
+}
+
+// executionContext contains the effective values of the execution context of the command.
+type executionContext struct {
+	cpus              []int
+	credentialChanged bool
+	credentialKnown   bool
+	cwd               string
+	gid               int
+	groups            []int
+	ioniceClass       string
+	ioniceLevel       int
+	landlock          *landlock
+	limits            []helperRlimit
+	nice              int
+	niceKnown         bool
+	sandbox           *sandbox
+	seccomp           *seccompFilter
+	uid               int
+	umask             int
+}
+
+// AddFields adds the execution context to the given logging context.
+func (e *executionContext) AddFields(ctx zerolog.Context) zerolog.Context {
+	ctx = ctx.Str("cwd", e.cwd)
+	if e.credentialKnown {
+		ctx = ctx.Int("uid", e.uid).
+			Int("gid", e.gid).
+			Ints("groups", e.groups)
+	}
+	if e.umask >= 0 {
+		ctx = ctx.Str("umask", fmt.Sprintf("%04o", e.umask))
+	}
+	if e.niceKnown {
+		ctx = ctx.Int("nice", e.nice)
+	}
+	if e.ioniceClass != "" {
+		ctx = ctx.Str("ionice_class", e.ioniceClass)
+		if e.ioniceClass == config.IONiceBestEffort || e.ioniceClass == config.IONiceRealtime {
+			ctx = ctx.Int("ionice_level", e.ioniceLevel)
+		}
+	}
+	if len(e.cpus) > 0 {
+		ctx = ctx.Str("cpu_affinity", formatCPUList(e.cpus))
+	}
+	if e.sandbox != nil {
+		ctx = e.sandbox.AddFields(ctx)
+	}
+	if e.landlock != nil {
+		ctx = e.landlock.AddFields(ctx)
+	}
+	if e.seccomp != nil {
+		ctx = e.seccomp.AddFields(ctx)
+	}
+	if len(e.limits) > 0 {
+		limits := zerolog.Dict()
+		for _, limit := range e.limits {
+			limits = limits.Uint64(limit.Name, limit.Cur)
+		}
+		ctx = ctx.Dict("limits", limits)
+	}
+	return ctx
+}
+
+// launcher starts the command from a dedicated OS thread.
+//
+// On Linux, the niceness, I/O scheduling priority and CPU affinity belong to individual threads and are inherited by
+// any process forked from them. The launcher applies them to its own thread so that they take effect in the command
+// from its very first instruction without affecting json-exec itself. The thread is never returned to the Go
+// runtime and stays alive until the launcher is closed so that settings tied to the parent thread, such as the parent
+// death signal, behave as expected.
+type launcher struct {
+	// unexported members
+	cfg      *config.RunConfig
+	cgroup   *cgroup
+	commands chan *exec.Cmd
+	context  *executionContext
+	done     chan struct{}
+	helper   *helperProcess
+	results  chan error
+}
+
+// newLauncher prepares the thread from which the command is started and returns the launcher.
+//
+// If cg is not nil, the command is moved into the cgroup before the program is executed.
+func newLauncher(cfg *config.RunConfig, cg *cgroup) (*launcher, error) {
+	l := &launcher{
+		cfg:      cfg,
+		cgroup:   cg,
+		commands: make(chan *exec.Cmd),
+		done:     make(chan struct{}),
+		results:  make(chan error, 1),
+	}
+	ready := make(chan error, 1)
+	go func() {
+		// the thread is deliberately never unlocked so that it exits with this goroutine
+		runtime.LockOSThread()
+
+		ctx, err := l.prepare()
+		l.context = ctx
+		ready <- err
+		if err != nil {
+			return
+		}
+		select {
+		case command := <-l.commands:
+			l.results <- l.start(command)
+		case <-l.done:
+			return
+		}
+		<-l.done
+	}()
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// prepare applies the settings of the execution context to the current thread and determines their effective values.
+func (l *launcher) prepare() (*executionContext, error) {
+	ctx := &executionContext{
+		cwd:   l.cfg.Process.Cwd,
+		umask: l.cfg.Process.Umask,
+	}
+	if ctx.cwd == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, &settingError{setting: settingWorkingDirectory, err: err}
+		}
+		ctx.cwd = cwd
+	} else if info, err := os.Stat(ctx.cwd); err != nil {
+		return nil, &settingError{setting: settingWorkingDirectory, err: err}
+	} else if !info.IsDir() {
+		return nil, &settingError{setting: settingWorkingDirectory, err: fmt.Errorf("'%s' is not a directory", ctx.cwd)}
+	}
+	if ctx.umask < 0 {
+		ctx.umask = readUmask()
+	} else if !umaskSupported {
+		return nil, &settingError{setting: "umask", err: fmt.Errorf("umask is not supported on this platform")}
+	}
+	if err := resolveCredential(&l.cfg.Process, ctx); err != nil {
+		return nil, err
+	}
+	if l.cfg.Sandbox.Enabled {
+		sandbox, err := newSandbox(&l.cfg.Sandbox, ctx)
+		if err != nil {
+			return nil, err
+		}
+		ctx.sandbox = sandbox
+	}
+	if l.cfg.Landlock.Enabled() {
+		landlock, err := newLandlock(&l.cfg.Landlock)
+		if err != nil {
+			return nil, err
+		}
+		ctx.landlock = landlock
+	}
+	if l.cfg.Seccomp.Enabled() {
+		filter, err := newSeccompFilter(&l.cfg.Seccomp)
+		if err != nil {
+			return nil, err
+		}
+		ctx.seccomp = filter
+	}
+	if err := applyThreadAttributes(&l.cfg.Process, ctx); err != nil {
+		return nil, err
+	}
+	limits, err := buildRlimits(&l.cfg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	ctx.limits = limits
+	return ctx, nil
+}
+
+// start starts the command, executing the helper in its place if any settings must be applied by the helper.
+func (l *launcher) start(command *exec.Cmd) error {
+	if len(l.context.limits) == 0 && l.cgroup == nil && l.context.sandbox == nil && l.context.landlock == nil &&
+		l.context.seccomp == nil {
+		applyCredential(command, l.context)
+		return startWithUmask(command, l.cfg.Process.Umask)
+	}
+
+	// the helper needs its privileges to set up the sandbox so it switches to the user of the command itself
+	spec := helperSpec{Rlimits: l.context.limits}
+	if l.context.landlock != nil {
+		spec.Landlock = l.context.landlock.HelperSpec()
+	}
+	if l.context.seccomp != nil {
+		filter, err := l.context.seccomp.Attach(command)
+		if err != nil {
+			return err
+		}
+		spec.Seccomp = filter
+	}
+	if l.context.sandbox != nil {
+		l.context.sandbox.Attach(command)
+		spec.Sandbox = l.context.sandbox.HelperSpec()
+		if l.context.credentialChanged {
+			spec.Credential = &helperCredential{
+				GID:    l.context.gid,
+				Groups: l.context.groups,
+				UID:    l.context.uid,
+			}
+		}
+	} else {
+		applyCredential(command, l.context)
+	}
+	helper, err := wrapWithHelper(command, spec, l.cgroup != nil)
+	if err != nil {
+		return err
+	}
+	l.helper = helper
+	err = startWithUmask(command, l.cfg.Process.Umask)
+	if err == nil && l.cgroup != nil {
+		if err := l.cgroup.Add(command.Process.Pid); err != nil {
+			log.Warn().Err(err).Msgf("failed to move command into cgroup, continuing without it: %s", err.Error())
+			l.cgroup = nil
+		}
+	}
+	if err == nil && l.context.seccomp != nil {
+		l.context.seccomp.Listen()
+	}
+	return helper.Wait(command, err)
+}
+
+// Cgroup returns the cgroup the command was moved into, if any.
+func (l *launcher) Cgroup() *cgroup {
+	return l.cgroup
+}
+
+// Seccomp returns the seccomp filter of the command, if any.
+func (l *launcher) Seccomp() *seccompFilter {
+	return l.context.seccomp
+}
+
+// Target returns the process to which signals meant for the command are sent, which must have been started.
+//
+// When the command runs in a sandbox, the helper stays behind as the init process of its PID namespace and ignores
+// any signal, so signals are sent to the program it started instead. Signals sent to the process group of the command
+// still reach the program since it stays in the process group of the helper.
+func (l *launcher) Target(command *exec.Cmd) *os.Process {
+	if l.context.sandbox == nil {
+		return command.Process
+	}
+	for _, pid := range childProcesses(command.Process.Pid) {
+		if process, err := os.FindProcess(pid); err == nil {
+			return process
+		}
+	}
+	return command.Process
+}
+
+// Status returns the signal which terminated the program as reported by the helper when it stayed behind as the init
+// process of the sandbox, which is nil otherwise.
+//
+// This function must only be called once the command has exited.
+func (l *launcher) Status() *helperStatus {
+	if l.helper == nil {
+		return nil
+	}
+	return l.helper.Status()
+}
+
+// Context returns the effective execution context of the command.
+func (l *launcher) Context() *executionContext {
+	return l.context
+}
+
+// Start starts the command from the prepared thread.
+//
+// This function may only be called once.
+func (l *launcher) Start(command *exec.Cmd) error {
+	command.Dir = l.cfg.Process.Cwd
+	l.commands <- command
+	return <-l.results
+}
+
+// Close allows the prepared thread to exit.
+func (l *launcher) Close() {
+	close(l.done)
+}
+
+// formatCPUList formats the sorted list of CPU numbers as a comma-separated list of numbers and ranges.
+func formatCPUList(cpus []int) string {
+	parts := []string{}
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
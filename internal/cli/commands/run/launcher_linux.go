@@ -0,0 +1,102 @@
+package run
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// constants for the ioprio_get and ioprio_set system calls
+const (
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// _ioprioClasses maps the I/O scheduling classes to their kernel values.
+var _ioprioClasses = map[string]int{
+	"none":                  0,
+	config.IONiceRealtime:   1,
+	config.IONiceBestEffort: 2,
+	config.IONiceIdle:       3,
+}
+
+// cpuMaskWordBits is the number of bits in each word of a CPU affinity mask.
+const cpuMaskWordBits = 32 << (^uintptr(0) >> 63)
+
+// cpuMask is a CPU affinity mask as used by the sched_getaffinity and sched_setaffinity system calls.
+type cpuMask [config.MaxCPUs / cpuMaskWordBits]uintptr
+
+// applyThreadAttributes applies the niceness, I/O scheduling priority and CPU affinity of the command to the
+// current thread and records their effective values in the execution context.
+func applyThreadAttributes(cfg *config.ProcessConfig, ctx *executionContext) error {
+	tid := syscall.Gettid()
+
+	// the kernel returns the niceness as 20 - nice to avoid negative return values
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, tid)
+	if err != nil {
+		return &settingError{setting: "nice", err: err}
+	}
+	ctx.nice = 20 - prio
+	ctx.niceKnown = true
+	if cfg.Nice != 0 {
+		nice := ctx.nice + cfg.Nice
+		if nice < -20 {
+			nice = -20
+		} else if nice > 19 {
+			nice = 19
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+			return &settingError{setting: "nice", err: err}
+		}
+		ctx.nice = nice
+	}
+
+	if cfg.IONiceClass != "" {
+		level := cfg.IONiceLevel
+		if cfg.IONiceClass == config.IONiceIdle {
+			level = 0
+		}
+		ioprio := _ioprioClasses[cfg.IONiceClass]<<ioprioClassShift | level
+		_, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio))
+		if errno != 0 {
+			return &settingError{setting: "I/O scheduling priority", err: errno}
+		}
+	}
+	ioprio, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(tid), 0)
+	if errno != 0 {
+		return &settingError{setting: "I/O scheduling priority", err: errno}
+	}
+	for name, class := range _ioprioClasses {
+		if class == int(ioprio)>>ioprioClassShift {
+			ctx.ioniceClass = name
+		}
+	}
+	ctx.ioniceLevel = int(ioprio) & (1<<ioprioClassShift - 1)
+
+	var mask cpuMask
+	if len(cfg.CPUAffinity) > 0 {
+		for _, cpu := range cfg.CPUAffinity {
+			mask[cpu/cpuMaskWordBits] |= 1 << uint(cpu%cpuMaskWordBits)
+		}
+		_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(tid), unsafe.Sizeof(mask),
+			uintptr(unsafe.Pointer(&mask)))
+		if errno != 0 {
+			return &settingError{setting: "CPU affinity", err: fmt.Errorf("%s (CPUs %s)", errno.Error(),
+				formatCPUList(cfg.CPUAffinity))}
+		}
+		mask = cpuMask{}
+	}
+	_, _, errno = syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, uintptr(tid), unsafe.Sizeof(mask),
+		uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return &settingError{setting: "CPU affinity", err: errno}
+	}
+	for cpu := 0; cpu < config.MaxCPUs; cpu++ {
+		if mask[cpu/cpuMaskWordBits]&(1<<uint(cpu%cpuMaskWordBits)) != 0 {
+			ctx.cpus = append(ctx.cpus, cpu)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// applyThreadAttributes returns an error if the niceness, I/O scheduling priority or CPU affinity of the command is
+// set since they can only be applied to individual threads on Linux.
+func applyThreadAttributes(cfg *config.ProcessConfig, ctx *executionContext) error {
+	switch {
+	case cfg.Nice != 0:
+		return &settingError{setting: "nice", err: fmt.Errorf("nice is only supported on Linux")}
+	case cfg.IONiceClass != "":
+		return &settingError{setting: "I/O scheduling priority",
+			err: fmt.Errorf("I/O scheduling priority is only supported on Linux")}
+	case len(cfg.CPUAffinity) > 0:
+		return &settingError{setting: "CPU affinity", err: fmt.Errorf("CPU affinity is only supported on Linux")}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package run
+
+import "testing"
+
+// TestFormatCPUList checks that consecutive CPU numbers are formatted as ranges.
+func TestFormatCPUList(t *testing.T) {
+	tests := []struct {
+		cpus []int
+		want string
+	}{
+		{cpus: nil, want: ""},
+		{cpus: []int{3}, want: "3"},
+		{cpus: []int{0, 1, 2, 3, 6}, want: "0-3,6"},
+		{cpus: []int{0, 2, 4}, want: "0,2,4"},
+		{cpus: []int{1, 2, 5, 6, 7, 9}, want: "1-2,5-7,9"},
+	}
+	for _, tt := range tests {
+		if got := formatCPUList(tt.cpus); got != tt.want {
+			t.Errorf("formatCPUList(%v) = %q, expected %q", tt.cpus, got, tt.want)
+		}
+	}
+}
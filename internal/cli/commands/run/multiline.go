@@ -0,0 +1,107 @@
+package run
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// lineGrouper groups consecutive lines of output from a single stream before passing them to a lineProcessor.
+//
+// A group is passed to the processor as a single line once a line which does not continue it arrives, it reaches
+// the maximum number of lines, no line arrives within the maximum wait time or Flush() is called.
+type lineGrouper struct {
+	// unexported members
+	cfg       *config.MultilineConfig
+	gen       int
+	group     []outputLine
+	mu        sync.Mutex
+	open      bool
+	processor *lineProcessor
+	started   bool
+	timer     *time.Timer
+}
+
+// newLineGrouper creates a new lineGrouper object using the given multiline options.
+func newLineGrouper(cfg *config.MultilineConfig, processor *lineProcessor) *lineGrouper {
+	return &lineGrouper{
+		cfg:       cfg,
+		processor: processor,
+	}
+}
+
+// Add adds the line to the current group or, if it does not continue the group, starts a new one.
+//
+// Lines which continue a group that has already been passed to the processor because it reached the maximum number
+// of lines or the maximum wait time start a new group which may be continued in the same way.
+func (g *lineGrouper) Add(line outputLine) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.open || !g.cfg.Continues(line.text, g.started) {
+		g.flush()
+		g.open = true
+		g.started = g.cfg.Starts(line.text)
+	}
+	g.group = append(g.group, line)
+	if g.cfg.MaxLines > 0 && len(g.group) >= g.cfg.MaxLines {
+		g.flush()
+	} else if g.cfg.MaxWait > 0 {
+		g.resetTimer()
+	}
+}
+
+// Flush passes any lines in the current group to the processor.
+func (g *lineGrouper) Flush() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.flush()
+	g.open = false
+}
+
+// flush passes the lines in the current group to the processor as a single line.
+func (g *lineGrouper) flush() {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	if len(g.group) == 0 {
+		return
+	}
+
+	grouped := outputLine{
+		lineCount: len(g.group),
+		number:    g.group[0].number,
+		stream:    g.group[0].stream,
+	}
+	texts := make([]string, 0, len(g.group))
+	for _, line := range g.group {
+		texts = append(texts, line.text)
+		grouped.totalBytes += line.totalBytes
+		grouped.truncated = grouped.truncated || line.truncated
+	}
+	grouped.text = strings.Join(texts, "\n")
+	g.group = nil
+	g.processor.Process(grouped)
+}
+
+// resetTimer restarts the timer which passes the current group to the processor once the maximum wait expires.
+func (g *lineGrouper) resetTimer() {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	g.gen++
+	gen := g.gen
+	g.timer = time.AfterFunc(g.cfg.MaxWait, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		// ignore the timer if it was reset after it expired but before it acquired the lock
+		if gen == g.gen {
+			g.flush()
+		}
+	})
+}
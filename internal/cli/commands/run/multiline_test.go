@@ -0,0 +1,164 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+	"gopkg.in/yaml.v3"
+)
+
+// messageRecorder is an io.Writer which records the messages written by the global logger.
+type messageRecorder struct {
+	// unexported members
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+// recordMessages replaces the global logger with one which writes to a new messageRecorder until the test ends.
+func recordMessages(t *testing.T) *messageRecorder {
+	r := &messageRecorder{}
+	saved := log.Logger
+	log.ReplaceGlobal(zerolog.New(r))
+	t.Cleanup(func() {
+		log.ReplaceGlobal(saved)
+	})
+	return r
+}
+
+// Write records the data written by the logger.
+func (r *messageRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+// Messages returns the decoded messages written so far.
+func (r *messageRecorder) Messages(t *testing.T) []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := []map[string]interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(r.buf.Bytes()))
+	for scanner.Scan() {
+		var message map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			t.Fatalf("failed to decode message %q: %s", scanner.Text(), err.Error())
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// TestLineGrouper checks that consecutive lines are grouped into single messages using the multiline options.
+func TestLineGrouper(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		lines  []string
+		groups []string
+	}{
+		{
+			name:   "disabled",
+			yaml:   "{}",
+			lines:  []string{"a", "b"},
+			groups: []string{"a", "b"},
+		},
+		{
+			name: "go preset",
+			yaml: "{preset: go}",
+			lines: []string{"starting", "panic: boom", "", "goroutine 1 [running]:", "main.main()",
+				"\t/src/main.go:5 +0x1d", "exit status 2", "next"},
+			groups: []string{"starting",
+				"panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x1d\nexit status 2", "next"},
+		},
+		{
+			name: "python preset",
+			yaml: "{preset: Python}",
+			lines: []string{"Traceback (most recent call last):", `  File "x.py", line 1, in <module>`,
+				"ValueError: bad", "done"},
+			groups: []string{"Traceback (most recent call last):\n  File \"x.py\", line 1, in <module>\n" +
+				"ValueError: bad", "done"},
+		},
+		{
+			name: "java preset",
+			yaml: "{preset: java}",
+			lines: []string{"java.lang.RuntimeException: x", "\tat Foo.bar(Foo.java:1)",
+				"Caused by: java.io.IOException", "\t... 1 more", "ok"},
+			groups: []string{"java.lang.RuntimeException: x\n\tat Foo.bar(Foo.java:1)\n" +
+				"Caused by: java.io.IOException\n\t... 1 more", "ok"},
+		},
+		{
+			name:   "start only",
+			yaml:   "{start: '^%{YEAR}-'}",
+			lines:  []string{"2024-01 a", "more", "2024-02 b"},
+			groups: []string{"2024-01 a\nmore", "2024-02 b"},
+		},
+		{
+			name:   "start and continuation",
+			yaml:   "{start: '^ERR', continuation: '^\\s'}",
+			lines:  []string{" x", "ERR y", " z", "w"},
+			groups: []string{" x", "ERR y\n z", "w"},
+		},
+		{
+			name:   "max lines",
+			yaml:   "{start: '^S', max_lines: 2}",
+			lines:  []string{"S", "a", "b", "S"},
+			groups: []string{"S\na", "b", "S"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.AppConfig
+			if err := yaml.Unmarshal([]byte("multiline: "+tt.yaml), &cfg.Run); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			recorder := recordMessages(t)
+			w := newLineWriter(config.StreamStderr, 0, newLineProcessor(&cfg, cfg.Run.Parse), &cfg.Run.Multiline)
+			w.Write([]byte(strings.Join(tt.lines, "\n")))
+			w.Flush()
+
+			groups := []string{}
+			for _, message := range recorder.Messages(t) {
+				groups = append(groups, message[zerolog.MessageFieldName].(string))
+			}
+			if !reflect.DeepEqual(groups, tt.groups) {
+				t.Errorf("groups = %q, expected %q", groups, tt.groups)
+			}
+		})
+	}
+}
+
+// TestLineGrouperMaxWait checks that a group is emitted once no line arrives within the maximum wait time.
+func TestLineGrouperMaxWait(t *testing.T) {
+	var cfg config.AppConfig
+	if err := yaml.Unmarshal([]byte("multiline: {start: '^S', max_wait: 10ms}"), &cfg.Run); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	recorder := recordMessages(t)
+	w := newLineWriter(config.StreamStdout, 0, newLineProcessor(&cfg, cfg.Run.Parse), &cfg.Run.Multiline)
+	w.Write([]byte("S\na\n"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.Messages(t)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	messages := recorder.Messages(t)
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, expected 1", len(messages))
+	}
+	if text := messages[0][zerolog.MessageFieldName]; text != "S\na" {
+		t.Errorf("message = %q, expected %q", text, "S\na")
+	}
+	if count := messages[0]["line_count"]; count != 2.0 {
+		t.Errorf("line_count = %v, expected 2", count)
+	}
+}
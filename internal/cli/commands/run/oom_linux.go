@@ -0,0 +1,21 @@
+package run
+
+import "path/filepath"
+
+// readOOMKillCount returns the number of processes in the application's cgroup which have been killed by the
+// OOM killer.
+//
+// The count is read from the memory.events file of the cgroup v2 hierarchy. If the count cannot be determined,
+// false is returned.
+func readOOMKillCount() (int64, bool) {
+	path, ok := ownCgroupPath()
+	if !ok {
+		return 0, false
+	}
+	events, err := readKeyedFile(filepath.Join(cgroupRoot, path, "memory.events"))
+	if err != nil {
+		return 0, false
+	}
+	count, ok := events["oom_kill"]
+	return count, ok
+}
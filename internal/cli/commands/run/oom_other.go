@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+// readOOMKillCount always returns false since OOM kills can only be detected on Linux.
+func readOOMKillCount() (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// outputCapture captures the output from a single stream of the command.
+//
+// At most limit bytes of the output are kept in memory. If a spill directory is given, the full output is also
+// written to a temporary file in that directory, which is kept only if the output in memory was truncated.
+type outputCapture struct {
+	// unexported members
+	ansi     string
+	buffer   *boundedBuffer
+	file     *os.File
+	hash     hash.Hash
+	spillErr error
+	stream   string
+}
+
+// newOutputCapture creates a new outputCapture object for the given stream name.
+//
+// The ANSI mode determines how escape sequences and carriage returns in the captured output are handled. The spill
+// file always contains the raw output.
+func newOutputCapture(stream string, limit int, spillDir string, ansi string) (*outputCapture, error) {
+	c := &outputCapture{
+		ansi:   ansi,
+		buffer: newBoundedBuffer(limit),
+		stream: stream,
+	}
+	if spillDir != "" {
+		file, err := os.CreateTemp(spillDir, fmt.Sprintf("json-exec-%s-*.log", stream))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file for %s output: %s", stream, err.Error())
+		}
+		c.file = file
+		c.hash = sha256.New()
+	}
+	return c, nil
+}
+
+// Write captures the data written to the stream.
+//
+// If the data cannot be written to the spill file, spilling is abandoned but the output is still captured in memory.
+func (c *outputCapture) Write(p []byte) (int, error) {
+	c.buffer.Write(p)
+	if c.file != nil && c.spillErr == nil {
+		c.hash.Write(p)
+		if _, err := c.file.Write(p); err != nil {
+			c.spillErr = err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the spill file, if there is one, removing it if the output was not truncated.
+func (c *outputCapture) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	if !c.buffer.Truncated() || c.spillErr != nil {
+		return os.Remove(c.file.Name())
+	}
+	return nil
+}
+
+// String returns the captured output after handling escape sequences and carriage returns.
+func (c *outputCapture) String() string {
+	return cleanOutput(c.buffer.String(), c.ansi)
+}
+
+// ExtractFields applies the extraction rules to each line of the captured output.
+//
+// Extracted fields are added to the given fields, with values from later lines overwriting those from earlier ones.
+func (c *outputCapture) ExtractFields(rules []config.ExtractRule, fields map[string]interface{}) {
+	for _, line := range strings.Split(c.String(), "\n") {
+		extractFields(rules, c.stream, strings.TrimSuffix(line, "\r"), fields)
+	}
+}
+
+// AddFields adds the captured output and details about it to the logger context.
+func (c *outputCapture) AddFields(ctx zerolog.Context) zerolog.Context {
+	ctx = ctx.Str(c.stream, c.String())
+	if c.buffer.Truncated() {
+		ctx = ctx.
+			Bool(c.stream+"_truncated", true).
+			Int64(c.stream+"_total_bytes", c.buffer.Total())
+		if c.spillErr != nil {
+			ctx = ctx.Str(c.stream+"_file_error", c.spillErr.Error())
+		} else if c.file != nil {
+			ctx = ctx.
+				Str(c.stream+"_file", c.file.Name()).
+				Str(c.stream+"_sha256", hex.EncodeToString(c.hash.Sum(nil)))
+		}
+	}
+	return ctx
+}
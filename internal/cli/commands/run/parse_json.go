@@ -0,0 +1,24 @@
+package run
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// parseJSONLine parses a line of output containing a JSON object.
+//
+// Numbers are kept as json.Number values so that large integers do not lose precision.
+func parseJSONLine(text string) (map[string]interface{}, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+		return nil, false
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(trimmed)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil || decoder.More() {
+		return nil, false
+	}
+	return fields, true
+}
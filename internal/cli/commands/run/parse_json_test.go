@@ -0,0 +1,40 @@
+package run
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestParseJSONLine checks that only lines containing a single JSON object are parsed.
+func TestParseJSONLine(t *testing.T) {
+	tests := []struct {
+		text string
+		want map[string]interface{}
+		ok   bool
+	}{
+		{text: `{"level":"info","msg":"hello"}`, want: map[string]interface{}{"level": "info", "msg": "hello"},
+			ok: true},
+		{text: `  {"n": 12345678901234567890}  `,
+			want: map[string]interface{}{"n": json.Number("12345678901234567890")}, ok: true},
+		{text: `{"nested":{"a":[1,true,null]}}`, want: map[string]interface{}{
+			"nested": map[string]interface{}{"a": []interface{}{json.Number("1"), true, nil}}}, ok: true},
+		{text: `{}`, want: map[string]interface{}{}, ok: true},
+		{text: `plain text`},
+		{text: `[1, 2]`},
+		{text: `{"a": 1} trailing`},
+		{text: `{"a": 1} {"b": 2}`},
+		{text: `{"a": }`},
+		{text: ``},
+	}
+	for _, tt := range tests {
+		got, ok := parseJSONLine(tt.text)
+		if ok != tt.ok {
+			t.Errorf("parseJSONLine(%q) ok = %t, expected %t", tt.text, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseJSONLine(%q) = %v, expected %v", tt.text, got, tt.want)
+		}
+	}
+}
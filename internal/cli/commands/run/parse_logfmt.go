@@ -0,0 +1,144 @@
+package run
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// newLogfmtParser creates a lineParser which parses lines of logfmt-style key=value pairs.
+//
+// Keys without a value are treated as boolean flags with a value of true. Unquoted values which look like integers,
+// floating point numbers or booleans are converted to those types while quoted values are always strings. A line is
+// only considered to be logfmt if every key is valid and at least one key has a value.
+func newLogfmtParser(cfg config.LogfmtConfig) lineParser {
+	return func(text string) (map[string]interface{}, bool) {
+		fields := map[string]interface{}{}
+		counts := map[string]int{}
+		pairs := 0
+		s := strings.TrimSpace(text)
+		for len(s) > 0 {
+			// read the key
+			end := strings.IndexAny(s, "= \t")
+			if end < 0 {
+				end = len(s)
+			}
+			key := s[:end]
+			if !isLogfmtKey(key) {
+				return nil, false
+			}
+			s = s[end:]
+
+			// read the value
+			var value interface{} = true
+			if strings.HasPrefix(s, "=") {
+				var ok bool
+				if value, s, ok = readLogfmtValue(s[1:], cfg.Quoting); !ok {
+					return nil, false
+				}
+				pairs++
+			}
+			addLogfmtField(fields, counts, key, value, cfg.DuplicateKeys)
+			s = strings.TrimLeft(s, " \t")
+		}
+		if pairs == 0 {
+			return nil, false
+		}
+		return fields, true
+	}
+}
+
+// addLogfmtField adds the value to the fields using the given policy for keys which appear more than once.
+//
+// When the policy is to collect the values into an array, counts tracks the number of times each key has been seen
+// so that only keys which actually appear more than once are converted into arrays.
+func addLogfmtField(fields map[string]interface{}, counts map[string]int, key string, value interface{},
+	duplicateKeys string) {
+
+	counts[key]++
+	switch {
+	case counts[key] == 1:
+		fields[key] = value
+	case duplicateKeys == config.DuplicateKeysArray && counts[key] == 2:
+		fields[key] = []interface{}{fields[key], value}
+	case duplicateKeys == config.DuplicateKeysArray:
+		fields[key] = append(fields[key].([]interface{}), value)
+	case duplicateKeys == config.DuplicateKeysLast:
+		fields[key] = value
+	}
+}
+
+// isLogfmtKey returns whether or not the string is a valid logfmt key.
+func isLogfmtKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == '@', r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// readLogfmtValue reads a value from the start of the string and returns the value along with the remainder of the
+// string.
+func readLogfmtValue(s string, quoting string) (interface{}, string, bool) {
+	if s == "" {
+		return "", s, true
+	}
+
+	quote := s[0]
+	if (quote == '"' && quoting != config.QuotingNone) || (quote == '\'' && quoting == config.QuotingAny) {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case quote:
+				value, ok := unquoteLogfmtValue(s[:i+1])
+				return value, s[i+1:], ok
+			}
+		}
+		return nil, s, false // unterminated quote
+	}
+
+	end := strings.IndexAny(s, " \t")
+	if end < 0 {
+		end = len(s)
+	}
+	return typedLogfmtValue(s[:end]), s[end:], true
+}
+
+// unquoteLogfmtValue removes the quotes surrounding the value and interprets any escape sequences.
+func unquoteLogfmtValue(s string) (string, bool) {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	value, err := strconv.Unquote(s)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// typedLogfmtValue converts an unquoted value into an integer, floating point number or boolean if possible.
+func typedLogfmtValue(s string) interface{} {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestLogfmtParser checks that logfmt output lines are parsed into typed fields.
+func TestLogfmtParser(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.LogfmtConfig
+		text string
+		want map[string]interface{}
+		ok   bool
+	}{
+		{name: "types", text: `level=info n=42 f=1.5 ok=true no=false s=abc`, want: map[string]interface{}{
+			"level": "info", "n": int64(42), "f": 1.5, "ok": true, "no": false, "s": "abc"}, ok: true},
+		{name: "quoted", text: `msg="hello \"world\"" n="42" empty=`, want: map[string]interface{}{
+			"msg": `hello "world"`, "n": "42", "empty": ""}, ok: true},
+		{name: "flag", text: `  debug  user.id=7	@ts=now `, want: map[string]interface{}{
+			"debug": true, "user.id": int64(7), "@ts": "now"}, ok: true},
+		{name: "not a number", text: `a=NaN b=Inf`, want: map[string]interface{}{"a": "NaN", "b": "Inf"}, ok: true},
+		{name: "no values", text: `just some words`},
+		{name: "invalid key", text: `key=value (extra)`},
+		{name: "unterminated quote", text: `msg="hello`},
+		{name: "empty", text: ``},
+
+		// quoting
+		{name: "single quotes not allowed", text: `msg='a b'`},
+		{name: "single quotes", cfg: config.LogfmtConfig{Quoting: config.QuotingAny}, text: `msg='it\'s "ok"'`,
+			want: map[string]interface{}{"msg": `it's "ok"`}, ok: true},
+		{name: "no quoting", cfg: config.LogfmtConfig{Quoting: config.QuotingNone}, text: `msg="a`,
+			want: map[string]interface{}{"msg": `"a`}, ok: true},
+
+		// duplicate keys
+		{name: "last", cfg: config.LogfmtConfig{DuplicateKeys: config.DuplicateKeysLast}, text: `a=1 a=2 a=3`,
+			want: map[string]interface{}{"a": int64(3)}, ok: true},
+		{name: "first", cfg: config.LogfmtConfig{DuplicateKeys: config.DuplicateKeysFirst}, text: `a=1 a=2 a=3`,
+			want: map[string]interface{}{"a": int64(1)}, ok: true},
+		{name: "array", cfg: config.LogfmtConfig{DuplicateKeys: config.DuplicateKeysArray}, text: `a=1 a=2 a=3 b=x`,
+			want: map[string]interface{}{"a": []interface{}{int64(1), int64(2), int64(3)}, "b": "x"}, ok: true},
+	}
+	for _, tt := range tests {
+		cfg := tt.cfg
+		if cfg.Quoting == "" {
+			cfg.Quoting = config.QuotingDouble
+		}
+		if cfg.DuplicateKeys == "" {
+			cfg.DuplicateKeys = config.DuplicateKeysLast
+		}
+		got, ok := newLogfmtParser(cfg)(tt.text)
+		if ok != tt.ok {
+			t.Errorf("%s: parse(%q) ok = %t, expected %t", tt.name, tt.text, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parse(%q) = %v, expected %v", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// policyDecision contains the result of evaluating the policy for a command.
+type policyDecision struct {
+	// unexported members
+	allowed bool
+	file    *os.File
+	path    string
+	pinned  string
+	rule    string
+	sha256  string
+}
+
+// evaluatePolicy determines whether or not the command with the given arguments may be executed.
+//
+// cwd is the working directory of the command, against which a relative path to the command is resolved. If empty,
+// the working directory of json-exec is used.
+func evaluatePolicy(cfg *config.PolicyConfig, args []string, cwd string) (*policyDecision, error) {
+	d := &policyDecision{
+		path: resolveBinary(args[0], cwd),
+	}
+	if d.path != "" && cfg.NeedsSHA256() {
+		file, sum, err := hashFile(d.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute checksum of %s: %s", d.path, err.Error())
+		}
+		d.file = file
+		d.sha256 = sum
+	}
+
+	argLine := strings.Join(args[1:], " ")
+	for _, rule := range cfg.Rules {
+		if rule.Path != "" && (d.path == "" || rule.Path != d.path) {
+			continue
+		}
+		if rule.Glob != "" {
+			if matched, _ := filepath.Match(rule.Glob, d.path); d.path == "" || !matched {
+				continue
+			}
+		}
+		if rule.SHA256 != "" && (d.sha256 == "" || rule.SHA256 != d.sha256) {
+			continue
+		}
+		if rule.ArgsRegexp != nil && !rule.ArgsRegexp.MatchString(argLine) {
+			continue
+		}
+		d.allowed = rule.Action == config.PolicyAllow
+		d.rule = rule.Name
+		if !d.allowed {
+			d.Close()
+		}
+		return d, nil
+	}
+	d.allowed = cfg.Default == config.PolicyAllow
+	if !d.allowed {
+		d.Close()
+	}
+	return d, nil
+}
+
+// AddFields adds the decision to the given logging context.
+func (d *policyDecision) AddFields(ctx zerolog.Context) zerolog.Context {
+	policy := zerolog.Dict().
+		Bool("allowed", d.allowed)
+	if d.rule != "" {
+		policy = policy.Str("rule", d.rule)
+	}
+	if d.path != "" {
+		policy = policy.Str("path", d.path)
+	}
+	if d.sha256 != "" {
+		policy = policy.Str("sha256", d.sha256)
+	}
+	return ctx.Dict("policy", policy)
+}
+
+// Close closes the binary which was checked against the policy, if it was kept open.
+func (d *policyDecision) Close() {
+	if d.file != nil {
+		d.file.Close()
+		d.file = nil
+	}
+}
+
+// RestorePath replaces the path of the pinned binary in an error returned when starting the command with the path
+// of the binary so that the error refers to the binary the user knows.
+func (d *policyDecision) RestorePath(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok && d.pinned != "" && pathErr.Path == d.pinned {
+		return &os.PathError{Op: pathErr.Op, Path: d.path, Err: pathErr.Err}
+	}
+	return err
+}
+
+// Reason returns a description of why the command was allowed or denied.
+func (d *policyDecision) Reason() string {
+	if d.rule == "" {
+		return "the default policy"
+	}
+	return fmt.Sprintf("policy rule %s", d.rule)
+}
+
+// resolveBinary returns the absolute path of the binary executed for the command after resolving symbolic links.
+//
+// An empty string is returned if the binary cannot be found.
+func resolveBinary(name string, cwd string) string {
+	path := name
+	if !strings.ContainsRune(name, '/') && !strings.ContainsRune(name, filepath.Separator) {
+		p, err := exec.LookPath(name)
+		if err != nil {
+			return ""
+		}
+		path = p
+	} else if !filepath.IsAbs(path) && cwd != "" {
+		path = filepath.Join(cwd, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// hashFile opens the given file and returns it along with its hex-encoded SHA-256 checksum.
+//
+// The file is kept open so that the binary which was hashed can be executed even if the path is replaced afterwards.
+func hashFile(path string) (*os.File, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	return f, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,20 @@
+package run
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Pin configures the command to execute the binary which was checked against the policy through the file descriptor
+// it was hashed from, so that replacing the binary after it was hashed has no effect.
+//
+// The descriptor is inherited by the program, and scripts see its path rather than their own path in $0 since
+// it is what the kernel passes to their interpreter.
+func (d *policyDecision) Pin(command *exec.Cmd) {
+	if d.file == nil {
+		return
+	}
+	command.ExtraFiles = append(command.ExtraFiles, d.file)
+	d.pinned = fmt.Sprintf("/proc/self/fd/%d", 2+len(command.ExtraFiles))
+	command.Path = d.pinned
+}
@@ -0,0 +1,101 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// TestEvaluatePolicy checks that the first rule matching the binary and arguments of the command decides whether or
+// not it is executed.
+func TestEvaluatePolicy(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temporary directory: %s", err.Error())
+	}
+	for _, name := range []string{"bin", "sbin"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err.Error())
+		}
+	}
+	binaries := map[string]string{"bin/tool": "tool", "bin/other": "other", "bin/third": "third", "sbin/admin": "admin"}
+	for name, content := range binaries {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatalf("failed to create binary: %s", err.Error())
+		}
+	}
+	if err := os.Symlink("tool", filepath.Join(dir, "bin", "link")); err != nil {
+		t.Fatalf("failed to create symbolic link: %s", err.Error())
+	}
+	os.Setenv("JSON_EXEC_TEST_POLICY", dir)
+	defer os.Unsetenv("JSON_EXEC_TEST_POLICY")
+
+	sum := sha256.Sum256([]byte("other"))
+	data := `
+rules:
+  - {name: no-force, action: deny, args: '^-rf\b'}
+  - {name: tool, action: allow, path: $JSON_EXEC_TEST_POLICY/bin/link}
+  - {name: hashed, action: allow, sha256: ` + hex.EncodeToString(sum[:]) + `}
+  - {name: admin, action: allow, glob: $JSON_EXEC_TEST_POLICY/sbin/*, args: '^status$'}
+`
+	var cfg config.PolicyConfig
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		cwd     string
+		allowed bool
+		rule    string
+		path    string
+	}{
+		{name: "path", args: []string{dir + "/bin/tool", "x"}, allowed: true, rule: "tool", path: "bin/tool"},
+		{name: "symbolic link", args: []string{dir + "/bin/link"}, allowed: true, rule: "tool", path: "bin/tool"},
+		{name: "relative to cwd", args: []string{"./bin/tool"}, cwd: dir, allowed: true, rule: "tool",
+			path: "bin/tool"},
+		{name: "args", args: []string{dir + "/bin/tool", "-rf", "/"}, allowed: false, rule: "no-force",
+			path: "bin/tool"},
+		{name: "sha256", args: []string{dir + "/bin/other"}, allowed: true, rule: "hashed", path: "bin/other"},
+		{name: "glob", args: []string{dir + "/sbin/admin", "status"}, allowed: true, rule: "admin",
+			path: "sbin/admin"},
+		{name: "glob with other args", args: []string{dir + "/sbin/admin", "stop"}, allowed: false,
+			path: "sbin/admin"},
+		{name: "no match", args: []string{dir + "/bin/third"}, allowed: false, path: "bin/third"},
+		{name: "not found", args: []string{"json-exec-no-such-binary"}, allowed: false},
+	}
+	for _, tt := range tests {
+		d, err := evaluatePolicy(&cfg, tt.args, tt.cwd)
+		if err != nil {
+			t.Errorf("%s: evaluatePolicy() failed: %s", tt.name, err.Error())
+			continue
+		}
+		path := ""
+		if tt.path != "" {
+			path = filepath.Join(dir, tt.path)
+		}
+		if d.allowed != tt.allowed || d.rule != tt.rule || d.path != path {
+			t.Errorf("%s: evaluatePolicy() = %t, %q, %s, expected %t, %q, %s", tt.name, d.allowed, d.rule, d.path,
+				tt.allowed, tt.rule, path)
+		}
+		if tt.path != "" {
+			want := sha256.Sum256([]byte(binaries[tt.path]))
+			if d.sha256 != hex.EncodeToString(want[:]) {
+				t.Errorf("%s: sha256 = %s, expected %s", tt.name, d.sha256, hex.EncodeToString(want[:]))
+			}
+		}
+		if (d.file != nil) != d.allowed && d.path != "" {
+			t.Errorf("%s: binary was kept open = %t, expected %t", tt.name, d.file != nil, d.allowed)
+		}
+		d.Close()
+	}
+}
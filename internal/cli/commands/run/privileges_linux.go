@@ -0,0 +1,47 @@
+package run
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// capabilities which allow json-exec to change the credentials and resource limits of the command
+const (
+	capSetGID      = 6
+	capSetUID      = 7
+	capSysResource = 24
+)
+
+// prctl option which keeps the current thread and its children from gaining privileges when executing a program
+const prSetNoNewPrivs = 38
+
+// hasCapability returns whether or not json-exec has the given capability in its effective set.
+func hasCapability(capability uint) bool {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return os.Geteuid() == 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "CapEff:") {
+			caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+			if err != nil {
+				return os.Geteuid() == 0
+			}
+			return caps&(1<<capability) != 0
+		}
+	}
+	return os.Geteuid() == 0
+}
+
+// setNoNewPrivileges keeps the current thread and any program it executes from gaining privileges, such as by
+// executing a setuid program.
+//
+// This is required to apply Landlock rules and seccomp filters without the CAP_SYS_ADMIN capability.
+func setNoNewPrivileges() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+	"os"
+)
+
+// capabilities which allow json-exec to change the credentials and resource limits of the command
+const (
+	capSetGID      = 6
+	capSetUID      = 7
+	capSysResource = 24
+)
+
+// hasCapability returns whether or not json-exec runs as root since capabilities are only supported on Linux.
+func hasCapability(capability uint) bool {
+	return os.Geteuid() == 0
+}
+
+// setNoNewPrivileges always returns an error since it is only supported on Linux.
+func setNoNewPrivileges() error {
+	return fmt.Errorf("no new privileges is only supported on Linux")
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup configures the command to start in its own process group so that any processes it spawns
+// can be signalled along with it.
+func setProcessGroup(command *exec.Cmd) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup sends the signal to every process in the process group of the given process.
+func signalProcessGroup(process *os.Process, sig syscall.Signal) error {
+	return syscall.Kill(-process.Pid, sig)
+}
@@ -0,0 +1,15 @@
+package run
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup does nothing on Windows since process groups cannot be signalled.
+func setProcessGroup(command *exec.Cmd) {}
+
+// signalProcessGroup kills the given process since Windows does not support sending signals to processes.
+func signalProcessGroup(process *os.Process, sig syscall.Signal) error {
+	return process.Kill()
+}
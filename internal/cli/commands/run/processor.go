@@ -0,0 +1,225 @@
+package run
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// outputLine is a single line of output from the command.
+type outputLine struct {
+	// unexported members
+	lineCount  int
+	number     int
+	stream     string
+	text       string
+	totalBytes int64
+	truncated  bool
+}
+
+// lineParser parses a line of output into fields.
+//
+// If the line is not in the expected format, false is returned.
+type lineParser func(text string) (map[string]interface{}, bool)
+
+// lineProcessor parses lines of output from the command and emits each one as a log message.
+type lineProcessor struct {
+	// unexported members
+	ansi     string
+	cfg      config.ParseConfig
+	combined bool
+	levels   *config.LevelConfig
+	mu       sync.Mutex
+	parser   lineParser
+	reserved map[string]bool
+	rules    []config.ExtractRule
+	seq      int
+}
+
+// newLineProcessor creates a new lineProcessor object using the given parse options.
+func newLineProcessor(cfg *config.AppConfig, parse config.ParseConfig) *lineProcessor {
+	p := &lineProcessor{
+		ansi:     cfg.Run.ANSI,
+		cfg:      parse,
+		combined: cfg.Run.Combined,
+		levels:   &cfg.Run.Levels,
+		rules:    cfg.Run.Extract,
+		reserved: map[string]bool{
+			zerolog.LevelFieldName:     true,
+			zerolog.MessageFieldName:   true,
+			zerolog.TimestampFieldName: true,
+			"command":                  true,
+			"args":                     true,
+			"stream":                   true,
+			"line":                     true,
+			"line_count":               true,
+			"seq":                      true,
+			"truncated":                true,
+			"total_bytes":              true,
+		},
+	}
+	for k := range cfg.Global.ExtraFields {
+		p.reserved[k] = true
+	}
+	switch parse.Format {
+	case config.ParseFormatJSON:
+		p.parser = parseJSONLine
+	case config.ParseFormatLogfmt:
+		p.parser = newLogfmtParser(parse.Logfmt)
+	}
+	return p
+}
+
+// Process parses the line and emits it as a log message.
+//
+// The level of the message is taken from the parsed fields if passthrough is enabled and they contain a level.
+// Otherwise, it is the level of the first matching level rule or, if none match, the level for the stream.
+func (p *lineProcessor) Process(line outputLine) {
+	line.text = cleanOutput(line.text, p.ansi)
+	level := p.levels.LevelOf(line.stream, line.text)
+	message := line.text
+	var fields map[string]interface{}
+	if p.parser != nil {
+		if parsed, ok := p.parser(line.text); ok {
+			fields = parsed
+		}
+	}
+	fields = extractFields(p.rules, line.stream, line.text, fields)
+	if fields != nil && p.cfg.Passthrough {
+		if l, ok := p.takeLevel(fields); ok {
+			level = l
+		}
+		if m, ok := p.takeMessage(fields); ok {
+			message = m
+		}
+	}
+
+	event := log.WithLevel(level)
+	if p.combined {
+		// hold the lock until the message is written so that messages are written in sequence
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.seq++
+		event = event.Int("seq", p.seq)
+	}
+	event = event.
+		Str("stream", line.stream).
+		Int("line", line.number)
+	if line.lineCount > 0 {
+		event = event.Int("line_count", line.lineCount)
+	}
+	if line.truncated {
+		event = event.
+			Bool("truncated", true).
+			Int64("total_bytes", line.totalBytes)
+	}
+	if len(fields) > 0 && p.cfg.KeyPrefix != "" {
+		prefixed := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			prefixed[p.cfg.KeyPrefix+k] = v
+		}
+		fields = prefixed
+	}
+	if len(fields) > 0 {
+		if p.cfg.Namespace != "" {
+			event = event.Interface(p.cfg.Namespace, fields)
+		} else {
+			event = event.Fields(p.resolveConflicts(fields))
+		}
+	}
+	event.Msg(message)
+}
+
+// resolveConflicts applies the conflict policy to any parsed fields which conflict with reserved fields.
+func (p *lineProcessor) resolveConflicts(fields map[string]interface{}) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if !p.reserved[k] {
+			resolved[k] = v
+		} else if p.cfg.ConflictPolicy == config.ConflictPolicyRename {
+			resolved[p.cfg.ConflictPrefix+k] = v
+		}
+	}
+	return resolved
+}
+
+// takeLevel removes the first recognized level field from the parsed fields and returns its level.
+func (p *lineProcessor) takeLevel(fields map[string]interface{}) (zerolog.Level, bool) {
+	for _, name := range p.cfg.LevelFields {
+		if v, ok := fields[name]; ok {
+			if level, ok := parseChildLevel(v); ok {
+				delete(fields, name)
+				return level, true
+			}
+		}
+	}
+	return zerolog.NoLevel, false
+}
+
+// takeMessage removes the first recognized message field from the parsed fields and returns its value.
+func (p *lineProcessor) takeMessage(fields map[string]interface{}) (string, bool) {
+	for _, name := range p.cfg.MessageFields {
+		if v, ok := fields[name].(string); ok {
+			delete(fields, name)
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// parseChildLevel converts a level parsed from an output line into a logging level.
+//
+// Levels may be common level names or the numeric levels used by libraries such as bunyan and pino.
+func parseChildLevel(v interface{}) (zerolog.Level, bool) {
+	switch value := v.(type) {
+	case string:
+		switch strings.ToLower(value) {
+		case "trace":
+			return zerolog.TraceLevel, true
+		case "debug", "dbug":
+			return zerolog.DebugLevel, true
+		case "info", "information", "informational", "notice":
+			return zerolog.InfoLevel, true
+		case "warn", "warning":
+			return zerolog.WarnLevel, true
+		case "error", "err", "eror":
+			return zerolog.ErrorLevel, true
+		case "fatal", "critical", "crit", "alert", "emerg", "emergency":
+			return zerolog.FatalLevel, true
+		case "panic":
+			return zerolog.PanicLevel, true
+		}
+	case json.Number:
+		if n, err := value.Float64(); err == nil {
+			return numericLevel(n)
+		}
+	case float64:
+		return numericLevel(value)
+	case int64:
+		return numericLevel(float64(value))
+	}
+	return zerolog.NoLevel, false
+}
+
+// numericLevel converts a bunyan/pino style numeric level into a logging level.
+func numericLevel(n float64) (zerolog.Level, bool) {
+	switch {
+	case n >= 60:
+		return zerolog.FatalLevel, true
+	case n >= 50:
+		return zerolog.ErrorLevel, true
+	case n >= 40:
+		return zerolog.WarnLevel, true
+	case n >= 30:
+		return zerolog.InfoLevel, true
+	case n >= 20:
+		return zerolog.DebugLevel, true
+	case n >= 10:
+		return zerolog.TraceLevel, true
+	}
+	return zerolog.NoLevel, false
+}
@@ -0,0 +1,41 @@
+package run
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// TestParseChildLevel checks that level names and numeric levels parsed from output lines are converted.
+func TestParseChildLevel(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want zerolog.Level
+		ok   bool
+	}{
+		{v: "TRACE", want: zerolog.TraceLevel, ok: true},
+		{v: "dbug", want: zerolog.DebugLevel, ok: true},
+		{v: "Notice", want: zerolog.InfoLevel, ok: true},
+		{v: "warning", want: zerolog.WarnLevel, ok: true},
+		{v: "err", want: zerolog.ErrorLevel, ok: true},
+		{v: "crit", want: zerolog.FatalLevel, ok: true},
+		{v: "panic", want: zerolog.PanicLevel, ok: true},
+		{v: "verbose", want: zerolog.NoLevel},
+		{v: json.Number("30"), want: zerolog.InfoLevel, ok: true},
+		{v: json.Number("x"), want: zerolog.NoLevel},
+		{v: 10.0, want: zerolog.TraceLevel, ok: true},
+		{v: 45.0, want: zerolog.WarnLevel, ok: true},
+		{v: int64(50), want: zerolog.ErrorLevel, ok: true},
+		{v: int64(60), want: zerolog.FatalLevel, ok: true},
+		{v: int64(5), want: zerolog.NoLevel},
+		{v: true, want: zerolog.NoLevel},
+		{v: nil, want: zerolog.NoLevel},
+	}
+	for _, tt := range tests {
+		got, ok := parseChildLevel(tt.v)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseChildLevel(%#v) = %s, %t, expected %s, %t", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// clockTicksPerSecond is the number of clock ticks per second used by /proc for CPU times (USER_HZ).
+//
+// This value is 100 on practically every Linux system and cannot be retrieved without cgo.
+const clockTicksPerSecond = 100
+
+// readProcessTree reads the resource usage of the process and all of its descendants from /proc.
+func readProcessTree(pid int) (processSample, error) {
+	sample := processSample{
+		cpuTicks: map[int]uint64{},
+	}
+	if _, err := os.Stat(filepath.Join("/proc", strconv.Itoa(pid))); err != nil {
+		return sample, err
+	}
+
+	// build a map of each process to its children
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return sample, err
+	}
+	stats := map[int]procStat{}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		p, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(p)
+		if err != nil {
+			continue // process exited
+		}
+		stats[p] = stat
+		children[stat.ppid] = append(children[stat.ppid], p)
+	}
+
+	// walk the tree starting at the process
+	pageSize := int64(os.Getpagesize())
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		stat, ok := stats[p]
+		if !ok {
+			continue
+		}
+		sample.processes++
+		sample.threads += stat.threads
+		sample.rssBytes += stat.rssPages * pageSize
+		sample.cpuTicks[p] = stat.cpuTicks
+		if fds, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(p), "fd")); err == nil {
+			sample.openFDs += len(fds)
+		}
+		queue = append(queue, children[p]...)
+	}
+	return sample, nil
+}
+
+// childProcesses returns the PIDs of the child processes of the given process.
+func childProcesses(pid int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, entry := range entries {
+		p, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if stat, err := readProcStat(p); err == nil && stat.ppid == pid {
+			pids = append(pids, p)
+		}
+	}
+	return pids
+}
+
+// procStat contains the fields of /proc/<pid>/stat which are used for sampling.
+type procStat struct {
+	cpuTicks uint64
+	ppid     int
+	rssPages int64
+	threads  int
+}
+
+// readProcStat reads and parses /proc/<pid>/stat.
+func readProcStat(pid int) (procStat, error) {
+	var stat procStat
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return stat, err
+	}
+
+	// the command name is surrounded by parentheses and may itself contain spaces or parentheses
+	s := string(data)
+	fields := strings.Fields(s[strings.LastIndexByte(s, ')')+1:])
+	if len(fields) < 22 {
+		return stat, os.ErrInvalid
+	}
+	if stat.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return stat, err
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return stat, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return stat, err
+	}
+	stat.cpuTicks = utime + stime
+	if stat.threads, err = strconv.Atoi(fields[17]); err != nil {
+		return stat, err
+	}
+	if stat.rssPages, err = strconv.ParseInt(fields[21], 10, 64); err != nil {
+		return stat, err
+	}
+	return stat, nil
+}
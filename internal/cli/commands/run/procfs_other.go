@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import "fmt"
+
+// clockTicksPerSecond is unused on this platform.
+const clockTicksPerSecond = 100
+
+// readProcessTree always returns an error since sampling is only supported on Linux.
+func readProcessTree(pid int) (processSample, error) {
+	return processSample{}, fmt.Errorf("resource sampling is only supported on Linux")
+}
+
+// childProcesses always returns nil since /proc is only read on Linux.
+func childProcesses(pid int) []int {
+	return nil
+}
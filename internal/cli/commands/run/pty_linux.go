@@ -0,0 +1,157 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// echo is the terminal local mode flag which echoes input characters, which the syscall package does not define.
+const echo = 0x8
+
+// pseudoTerminal is a pseudo-terminal in which the command is run.
+type pseudoTerminal struct {
+	// unexported members
+	done   chan struct{}
+	master *os.File
+	tty    *os.File
+}
+
+// openPty opens a new pseudo-terminal with a window of the given size.
+//
+// Output post-processing is disabled so that newlines are not translated into carriage return and newline pairs and
+// echoing is disabled so that input fed to the command does not appear in its output.
+func openPty(cols, rows int) (*pseudoTerminal, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	unlock := int32(0)
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, fmt.Errorf("failed to unlock terminal: %s", err.Error())
+	}
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		master.Close()
+		return nil, fmt.Errorf("failed to get terminal number: %s", err.Error())
+	}
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	p := &pseudoTerminal{
+		done:   make(chan struct{}),
+		master: master,
+		tty:    tty,
+	}
+
+	size := struct {
+		rows, cols, xpixel, ypixel uint16
+	}{
+		rows: uint16(rows),
+		cols: uint16(cols),
+	}
+	if err := ioctl(tty.Fd(), syscall.TIOCSWINSZ, unsafe.Pointer(&size)); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to set terminal window size: %s", err.Error())
+	}
+	var termios syscall.Termios
+	if err := ioctl(tty.Fd(), syscall.TCGETS, unsafe.Pointer(&termios)); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to get terminal attributes: %s", err.Error())
+	}
+	termios.Oflag &^= syscall.ONLCR
+	termios.Lflag &^= echo
+	if err := ioctl(tty.Fd(), syscall.TCSETS, unsafe.Pointer(&termios)); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to set terminal attributes: %s", err.Error())
+	}
+	return p, nil
+}
+
+// Attach configures the command to run in a new session with the terminal as its controlling terminal and as its
+// stdin, stdout and stderr.
+//
+// Since the command is the leader of the new session, it is also the leader of a new process group.
+func (p *pseudoTerminal) Attach(command *exec.Cmd) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Setpgid = false
+	command.SysProcAttr.Setsid = true
+	command.SysProcAttr.Setctty = true
+	command.SysProcAttr.Ctty = 0
+	command.Stdin = p.tty
+	command.Stdout = p.tty
+	command.Stderr = p.tty
+}
+
+// Start closes the terminal end used by the command, which must have been started, and copies all output from the
+// terminal to the writer until the command and any other process using the terminal have closed it.
+func (p *pseudoTerminal) Start(w io.Writer) {
+	p.tty.Close()
+	go func() {
+		defer close(p.done)
+		io.Copy(w, p.master) // reading fails with EIO once the terminal is closed
+	}()
+}
+
+// Feed copies the input to the terminal, which must have been started, followed by an end-of-file character so that
+// the command reads to the end of the input rather than waiting for more. If the input is nil, only the end-of-file
+// character is written.
+func (p *pseudoTerminal) Feed(r io.Reader) {
+	go func() {
+		w := &lastByteWriter{w: p.master, last: '\n'}
+		if r != nil {
+			io.Copy(w, r)
+		}
+		// an end-of-file character only signals the end of the input at the start of a line, otherwise it just
+		// completes the partial line
+		if w.last == '\n' {
+			p.master.Write([]byte{4})
+		} else {
+			p.master.Write([]byte{4, 4})
+		}
+	}()
+}
+
+// Wait waits for all of the output to be copied and then closes the terminal.
+func (p *pseudoTerminal) Wait() {
+	<-p.done
+	p.master.Close()
+}
+
+// Close closes the terminal without copying any output.
+func (p *pseudoTerminal) Close() {
+	p.tty.Close()
+	p.master.Close()
+}
+
+// lastByteWriter is an io.Writer which keeps track of the last byte written to the underlying writer.
+type lastByteWriter struct {
+	// unexported members
+	last byte
+	w    io.Writer
+}
+
+// Write writes the data to the underlying writer.
+func (w *lastByteWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if n > 0 {
+		w.last = p[n-1]
+	}
+	return n, err
+}
+
+// ioctl performs the ioctl system call on the file descriptor.
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// pseudoTerminal is not supported on this platform.
+type pseudoTerminal struct{}
+
+// openPty always returns an error since pseudo-terminals are only supported on Linux.
+func openPty(cols, rows int) (*pseudoTerminal, error) {
+	return nil, fmt.Errorf("pseudo-terminals are only supported on Linux")
+}
+
+// Attach does nothing.
+func (p *pseudoTerminal) Attach(command *exec.Cmd) {}
+
+// Start does nothing.
+func (p *pseudoTerminal) Start(w io.Writer) {}
+
+// Feed does nothing.
+func (p *pseudoTerminal) Feed(r io.Reader) {}
+
+// Wait does nothing.
+func (p *pseudoTerminal) Wait() {}
+
+// Close does nothing.
+func (p *pseudoTerminal) Close() {}
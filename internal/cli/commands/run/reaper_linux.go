@@ -0,0 +1,150 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go.sophtrust.dev/json-exec/internal/signals"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+const (
+	// prSetChildSubreaper is the prctl() option for marking the process as a child subreaper.
+	prSetChildSubreaper = 36
+
+	// pAll is the waitid() ID type for waiting on any child process.
+	pAll = 0
+)
+
+// reaper waits on orphaned processes which are re-parented to the application while the command runs so that they
+// do not accumulate as zombies.
+type reaper struct {
+	// unexported members
+	done    chan struct{}
+	pid     int
+	sigchld chan os.Signal
+	wg      sync.WaitGroup
+}
+
+// newReaper creates a new reaper object.
+//
+// If the application is not running as PID 1, it is marked as a child subreaper so that orphaned descendants of the
+// command are re-parented to it rather than to the system init process.
+func newReaper() (*reaper, error) {
+	if os.Getpid() != 1 {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+			return nil, fmt.Errorf("failed to become a child subreaper: %s", errno.Error())
+		}
+	}
+	r := &reaper{
+		done:    make(chan struct{}),
+		sigchld: make(chan os.Signal, 1),
+	}
+	signal.Notify(r.sigchld, syscall.SIGCHLD)
+	return r, nil
+}
+
+// Start begins reaping orphaned processes.
+//
+// The command itself, identified by pid, is never reaped so that its exit status can still be retrieved by waiting
+// on the command.
+func (r *reaper) Start(pid int) {
+	r.pid = pid
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-r.sigchld:
+				r.reap()
+			}
+		}
+	}()
+}
+
+// Stop stops reaping processes once any orphans which have already exited are reaped.
+func (r *reaper) Stop() {
+	signal.Stop(r.sigchld)
+	close(r.done)
+	r.wg.Wait()
+	r.reap()
+}
+
+// reap reaps every orphaned process which has exited.
+func (r *reaper) reap() {
+	for {
+		pid := r.peek()
+		if pid <= 0 {
+			return
+		}
+		if pid == r.pid {
+			// waitid() keeps returning the command until it is waited on, so wait on each other child instead
+			for _, child := range childProcesses(os.Getpid()) {
+				if child != r.pid {
+					r.wait(child)
+				}
+			}
+			return
+		}
+		if !r.wait(pid) {
+			return
+		}
+	}
+}
+
+// wait reaps the given child process if it has exited, returning whether or not it was reaped.
+func (r *reaper) wait(pid int) bool {
+	var status syscall.WaitStatus
+	for {
+		wpid, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || wpid <= 0 {
+			return false
+		}
+		break
+	}
+
+	logger := log.With().
+		Int("pid", pid).
+		Int("exit_code", status.ExitStatus()).
+		Logger()
+	if status.Signaled() {
+		logger = logger.With().
+			Str("signal", signals.Name(status.Signal())).
+			Logger()
+	}
+	logger.Info().Msgf("reaped orphaned process %d", pid)
+	return true
+}
+
+// peek returns the PID of a child process which has exited without actually reaping it.
+//
+// If there is no such process, 0 is returned.
+func (r *reaper) peek() int {
+	// siginfo_t is 128 bytes on all Linux systems and si_pid follows si_signo, si_errno and si_code, aligned to the
+	// size of a pointer
+	var siginfo [32]int32
+	index := 3
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		index = 4
+	}
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pAll, 0, uintptr(unsafe.Pointer(&siginfo[0])),
+			syscall.WEXITED|syscall.WNOHANG|syscall.WNOWAIT, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return 0
+		}
+		return int(siginfo[index])
+	}
+}
@@ -0,0 +1,80 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// TestReaper checks that orphaned descendants of the command are reaped while the command itself can still be waited
+// on.
+func TestReaper(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		exitCode int
+	}{
+		{
+			name:     "orphan exits normally",
+			script:   "sleep 0.2 >/dev/null & echo $!",
+			exitCode: 0,
+		},
+		{
+			name:     "orphan exits with error",
+			script:   "(sleep 0.2; exit 7) >/dev/null & echo $!",
+			exitCode: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordMessages(t)
+			r, err := newReaper()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			var stdout strings.Builder
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			cmd.Stdout = &stdout
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			r.Start(cmd.Process.Pid)
+			if err := cmd.Wait(); err != nil {
+				t.Fatalf("failed to wait on the command: %s", err.Error())
+			}
+			orphan, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+			if err != nil {
+				t.Fatalf("unexpected output %q", stdout.String())
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for len(recorder.Messages(t)) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			r.Stop()
+
+			messages := recorder.Messages(t)
+			if len(messages) != 1 {
+				t.Fatalf("got %d messages, expected 1", len(messages))
+			}
+			expected := fmt.Sprintf("reaped orphaned process %d", orphan)
+			if text := messages[0][zerolog.MessageFieldName]; text != expected {
+				t.Errorf("message = %q, expected %q", text, expected)
+			}
+			if code := messages[0]["exit_code"]; code != float64(tt.exitCode) {
+				t.Errorf("exit_code = %v, expected %d", code, tt.exitCode)
+			}
+			if _, err := os.Stat(filepath.Join("/proc", strconv.Itoa(orphan))); err == nil {
+				t.Errorf("process %d was not reaped", orphan)
+			}
+		})
+	}
+}
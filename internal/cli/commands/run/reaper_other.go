@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import "fmt"
+
+// reaper is not supported on this platform.
+type reaper struct{}
+
+// newReaper always returns an error since reaping orphaned processes is only supported on Linux.
+func newReaper() (*reaper, error) {
+	return nil, fmt.Errorf("init mode is only supported on Linux")
+}
+
+// Start does nothing.
+func (r *reaper) Start(pid int) {}
+
+// Stop does nothing.
+func (r *reaper) Stop() {}
@@ -0,0 +1,25 @@
+package run
+
+import (
+	"os"
+	"time"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// resourceUsage returns a dictionary containing the timing and resource usage of the command.
+//
+// The state may be nil if the command was never started, in which case only timing information is included.
+func resourceUsage(state *os.ProcessState, startTime, finishTime time.Time) *zerolog.Event {
+	dict := zerolog.Dict().
+		Time("started_at", startTime).
+		Time("finished_at", finishTime).
+		Int64("duration_ms", finishTime.Sub(startTime).Milliseconds())
+	if state != nil {
+		dict = dict.
+			Int64("user_cpu_ms", state.UserTime().Milliseconds()).
+			Int64("system_cpu_ms", state.SystemTime().Milliseconds())
+		dict = addSysUsage(dict, state)
+	}
+	return dict
+}
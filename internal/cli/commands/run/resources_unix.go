@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// addSysUsage adds the system-specific resource usage of the process to the dictionary.
+func addSysUsage(dict *zerolog.Event, state *os.ProcessState) *zerolog.Event {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return dict
+	}
+
+	// macOS reports the maximum resident set size in bytes while other systems report it in kilobytes
+	maxRSS := int64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+	return dict.
+		Int64("max_rss_bytes", maxRSS).
+		Int64("minor_page_faults", int64(rusage.Minflt)).
+		Int64("major_page_faults", int64(rusage.Majflt)).
+		Int64("voluntary_context_switches", int64(rusage.Nvcsw)).
+		Int64("involuntary_context_switches", int64(rusage.Nivcsw)).
+		Int64("block_input_ops", int64(rusage.Inblock)).
+		Int64("block_output_ops", int64(rusage.Oublock))
+}
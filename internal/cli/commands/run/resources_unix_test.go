@@ -0,0 +1,63 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// TestResourceUsage checks that the timing and, once the command has run, the resource usage are included.
+func TestResourceUsage(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "exit 0")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	startTime := time.Now()
+	finishTime := startTime.Add(1500 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		started bool
+		fields  []string
+	}{
+		{
+			name:   "never started",
+			fields: []string{"started_at", "finished_at", "duration_ms"},
+		},
+		{
+			name:    "finished",
+			started: true,
+			fields: []string{"started_at", "finished_at", "duration_ms", "user_cpu_ms", "system_cpu_ms",
+				"max_rss_bytes", "minor_page_faults", "major_page_faults", "voluntary_context_switches",
+				"involuntary_context_switches", "block_input_ops", "block_output_ops"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordMessages(t)
+			if tt.started {
+				log.Info().Dict("resources", resourceUsage(cmd.ProcessState, startTime, finishTime)).Send()
+			} else {
+				log.Info().Dict("resources", resourceUsage(nil, startTime, finishTime)).Send()
+			}
+
+			resources := recorder.Messages(t)[0]["resources"].(map[string]interface{})
+			if len(resources) != len(tt.fields) {
+				t.Errorf("resources = %v, expected fields %q", resources, tt.fields)
+			}
+			for _, field := range tt.fields {
+				if _, ok := resources[field]; !ok {
+					t.Errorf("resources is missing field %q", field)
+				}
+			}
+			if resources["duration_ms"] != 1500.0 {
+				t.Errorf("duration_ms = %v, expected 1500", resources["duration_ms"])
+			}
+		})
+	}
+}
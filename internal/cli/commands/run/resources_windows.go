@@ -0,0 +1,12 @@
+package run
+
+import (
+	"os"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// addSysUsage does nothing on Windows since only CPU times are available, which are already included.
+func addSysUsage(dict *zerolog.Event, state *os.ProcessState) *zerolog.Event {
+	return dict
+}
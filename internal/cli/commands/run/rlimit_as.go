@@ -0,0 +1,9 @@
+//go:build !openbsd && !windows
+// +build !openbsd,!windows
+
+package run
+
+import "syscall"
+
+// rlimitAddressSpace is the resource limit on the size of the virtual memory of a process.
+const rlimitAddressSpace = syscall.RLIMIT_AS
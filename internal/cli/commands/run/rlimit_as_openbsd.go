@@ -0,0 +1,4 @@
+package run
+
+// rlimitAddressSpace is negative since OpenBSD does not limit the size of the virtual memory of a process.
+const rlimitAddressSpace = -1
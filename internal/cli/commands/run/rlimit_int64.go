@@ -0,0 +1,27 @@
+//go:build dragonfly || freebsd
+// +build dragonfly freebsd
+
+package run
+
+import (
+	"math"
+	"syscall"
+)
+
+// newRlimit returns a resource limit with the given soft and hard limits.
+//
+// Resource limits are signed on this platform, so limits which are too large are treated as infinite.
+func newRlimit(cur, max uint64) syscall.Rlimit {
+	clamp := func(v uint64) int64 {
+		if v > math.MaxInt64 {
+			return math.MaxInt64
+		}
+		return int64(v)
+	}
+	return syscall.Rlimit{Cur: clamp(cur), Max: clamp(max)}
+}
+
+// rlimitValues returns the soft and hard limits of the resource limit.
+func rlimitValues(rlimit syscall.Rlimit) (uint64, uint64) {
+	return uint64(rlimit.Cur), uint64(rlimit.Max)
+}
@@ -0,0 +1,4 @@
+package run
+
+// rlimitNproc is the resource limit on the number of processes, which is missing from the syscall package.
+const rlimitNproc = 6
@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package run
+
+// rlimitNproc is the resource limit on the number of processes on BSD-derived systems, which is missing from the
+// syscall package.
+const rlimitNproc = 7
@@ -0,0 +1,16 @@
+//go:build !dragonfly && !freebsd && !windows
+// +build !dragonfly,!freebsd,!windows
+
+package run
+
+import "syscall"
+
+// newRlimit returns a resource limit with the given soft and hard limits.
+func newRlimit(cur, max uint64) syscall.Rlimit {
+	return syscall.Rlimit{Cur: cur, Max: max}
+}
+
+// rlimitValues returns the soft and hard limits of the resource limit.
+func rlimitValues(rlimit syscall.Rlimit) (uint64, uint64) {
+	return rlimit.Cur, rlimit.Max
+}
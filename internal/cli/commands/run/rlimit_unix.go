@@ -0,0 +1,121 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/json-exec/internal/errors"
+)
+
+// Names of resource limits.
+const (
+	limitAddressSpace = "address_space"
+	limitCore         = "core"
+	limitCPU          = "cpu"
+	limitFileSize     = "file_size"
+	limitOpenFiles    = "open_files"
+	limitProcesses    = "processes"
+)
+
+// buildRlimits returns the resource limits to set for the command, verifying that they can be set.
+//
+// The soft and hard limits are the same, except for the CPU time limit. Its hard limit is one second longer so that
+// the command receives SIGXCPU before being killed.
+func buildRlimits(cfg *config.LimitsConfig) ([]helperRlimit, error) {
+	limits := []helperRlimit{}
+	add := func(name string, resource int, value uint64) {
+		limits = append(limits, helperRlimit{Cur: value, Max: value, Name: name, Resource: resource})
+	}
+	if cfg.AddressSpace >= 0 {
+		if rlimitAddressSpace < 0 {
+			return nil, &settingError{setting: limitAddressSpace + " limit", err: fmt.Errorf(
+				"address space limit is not supported on this platform")}
+		}
+		add(limitAddressSpace, rlimitAddressSpace, uint64(cfg.AddressSpace))
+	}
+	if cfg.Core >= 0 {
+		add(limitCore, syscall.RLIMIT_CORE, uint64(cfg.Core))
+	}
+	if cfg.CPU > 0 {
+		seconds := uint64((cfg.CPU + time.Second - 1) / time.Second)
+		limits = append(limits, helperRlimit{Cur: seconds, Max: seconds + 1, Name: limitCPU,
+			Resource: syscall.RLIMIT_CPU})
+	}
+	if cfg.FileSize >= 0 {
+		add(limitFileSize, syscall.RLIMIT_FSIZE, uint64(cfg.FileSize))
+	}
+	if cfg.OpenFiles > 0 {
+		add(limitOpenFiles, syscall.RLIMIT_NOFILE, uint64(cfg.OpenFiles))
+	}
+	if cfg.Processes > 0 {
+		add(limitProcesses, rlimitNproc, uint64(cfg.Processes))
+	}
+
+	// only privileged processes may raise hard limits
+	for _, limit := range limits {
+		var current syscall.Rlimit
+		if err := syscall.Getrlimit(limit.Resource, &current); err != nil {
+			return nil, &settingError{setting: limit.Name + " limit", err: err}
+		}
+		if _, max := rlimitValues(current); limit.Max > max && !hasCapability(capSysResource) {
+			return nil, &settingError{setting: limit.Name + " limit", err: fmt.Errorf(
+				"raising the hard limit from %d to %d requires root privileges or the CAP_SYS_RESOURCE capability",
+				max, limit.Max)}
+		}
+	}
+	return limits, nil
+}
+
+// raiseRlimit raises the hard limit of the resource to that of the given limit, if it is higher, without changing the
+// soft limit.
+//
+// This function is called by the helper while it still has the privileges to raise hard limits. Since no limit is
+// lowered, the helper itself is not constrained by the limits.
+func raiseRlimit(limit helperRlimit) error {
+	var current syscall.Rlimit
+	if err := syscall.Getrlimit(limit.Resource, &current); err != nil {
+		return err
+	}
+	cur, max := rlimitValues(current)
+	if limit.Max <= max {
+		return nil
+	}
+	rlimit := newRlimit(cur, limit.Max)
+	return syscall.Setrlimit(limit.Resource, &rlimit)
+}
+
+// applyRlimit sets the soft and hard limits of the resource to those of the given limit.
+func applyRlimit(limit helperRlimit) error {
+	rlimit := newRlimit(limit.Cur, limit.Max)
+	return syscall.Setrlimit(limit.Resource, &rlimit)
+}
+
+// limitExceeded returns the name of the resource limit which caused the command to terminate, if any.
+//
+// The command receives SIGXCPU when it exceeds its CPU time limit and SIGXFSZ when it writes past its file size limit.
+// If it ignores SIGXCPU, it is killed when it reaches the hard CPU time limit. Shells exit with 128 plus the number
+// of the signal when one of their children is terminated by it, so those exit codes are treated the same way.
+func limitExceeded(cfg *config.LimitsConfig, state *os.ProcessState, status exitStatus) string {
+	if state == nil {
+		return ""
+	}
+	sig := status.signal
+	if !status.signaled && state.ExitCode() > errors.SignalBase {
+		sig = syscall.Signal(state.ExitCode() - errors.SignalBase)
+	}
+	switch {
+	case sig == syscall.SIGXCPU && cfg.CPU > 0:
+		return limitCPU
+	case sig == syscall.SIGXFSZ && cfg.FileSize >= 0:
+		return limitFileSize
+	case status.signaled && sig == syscall.SIGKILL && cfg.CPU > 0 && state.UserTime()+state.SystemTime() >= cfg.CPU:
+		return limitCPU
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package run
+
+import (
+	"fmt"
+	"os"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// buildRlimits returns an error if any resource limit is set since Windows does not support them.
+func buildRlimits(cfg *config.LimitsConfig) ([]helperRlimit, error) {
+	if cfg.Enabled() {
+		return nil, &settingError{setting: "limits", err: fmt.Errorf("resource limits are not supported on Windows")}
+	}
+	return nil, nil
+}
+
+// limitExceeded always returns an empty string since Windows does not support resource limits.
+func limitExceeded(cfg *config.LimitsConfig, state *os.ProcessState, status exitStatus) string {
+	return ""
+}
@@ -0,0 +1,142 @@
+package run
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// processSample contains the resource usage of a process tree at a single point in time.
+type processSample struct {
+	// unexported members
+	cpuTicks  map[int]uint64
+	openFDs   int
+	processes int
+	rssBytes  int64
+	threads   int
+}
+
+// sampler periodically samples the resource usage of the command and all of its descendants.
+type sampler struct {
+	// unexported members
+	count         int
+	done          chan struct{}
+	mu            sync.Mutex
+	peakCPU       float64
+	peakOpenFDs   int
+	peakProcesses int
+	peakRSSBytes  int64
+	peakThreads   int
+	wg            sync.WaitGroup
+}
+
+// startSampler starts sampling the resource usage of the process tree rooted at pid at the given interval and
+// emitting a message for each sample.
+func startSampler(pid int, interval time.Duration) *sampler {
+	s := &sampler{
+		done: make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous, err := readProcessTree(pid)
+		if err != nil {
+			log.Warn().Err(err).Msgf("unable to sample resource usage: %s", err.Error())
+			return
+		}
+		previousTime := time.Now()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+			current, err := readProcessTree(pid)
+			if err != nil {
+				continue // the command has most likely exited
+			}
+			currentTime := time.Now()
+			s.record(current, cpuPercent(previous, current, currentTime.Sub(previousTime)))
+			previous, previousTime = current, currentTime
+		}
+	}()
+	return s
+}
+
+// Stop stops sampling and returns a dictionary containing the peak values of all samples.
+//
+// If no samples were taken, nil is returned.
+func (s *sampler) Stop() *zerolog.Event {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return nil
+	}
+	return zerolog.Dict().
+		Int("samples", s.count).
+		Float64("cpu_percent", s.peakCPU).
+		Int64("rss_bytes", s.peakRSSBytes).
+		Int("open_fds", s.peakOpenFDs).
+		Int("threads", s.peakThreads).
+		Int("processes", s.peakProcesses)
+}
+
+// record emits a message for the sample and updates the peak values.
+func (s *sampler) record(sample processSample, cpu float64) {
+	s.mu.Lock()
+	s.count++
+	count := s.count
+	if cpu > s.peakCPU {
+		s.peakCPU = cpu
+	}
+	if sample.rssBytes > s.peakRSSBytes {
+		s.peakRSSBytes = sample.rssBytes
+	}
+	if sample.openFDs > s.peakOpenFDs {
+		s.peakOpenFDs = sample.openFDs
+	}
+	if sample.threads > s.peakThreads {
+		s.peakThreads = sample.threads
+	}
+	if sample.processes > s.peakProcesses {
+		s.peakProcesses = sample.processes
+	}
+	s.mu.Unlock()
+
+	log.Info().
+		Int("sample", count).
+		Float64("cpu_percent", cpu).
+		Int64("rss_bytes", sample.rssBytes).
+		Int("open_fds", sample.openFDs).
+		Int("threads", sample.threads).
+		Int("processes", sample.processes).
+		Msg("resource usage sample")
+}
+
+// cpuPercent calculates the CPU usage of the process tree between two samples as a percentage of a single CPU,
+// rounded to 2 decimal places.
+//
+// Processes which only appear in the current sample are counted from when they were started.
+func cpuPercent(previous, current processSample, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	var ticks uint64
+	for pid, t := range current.cpuTicks {
+		if p, ok := previous.cpuTicks[pid]; ok && p <= t {
+			ticks += t - p
+		} else if !ok {
+			ticks += t
+		}
+	}
+	return math.Round(float64(ticks)/clockTicksPerSecond/elapsed.Seconds()*10000) / 100
+}
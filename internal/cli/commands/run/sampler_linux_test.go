@@ -0,0 +1,103 @@
+package run
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCPUPercent checks that the CPU usage of a process tree is calculated from the ticks of both samples.
+func TestCPUPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous map[int]uint64
+		current  map[int]uint64
+		elapsed  time.Duration
+		expected float64
+	}{
+		{
+			name:     "idle",
+			previous: map[int]uint64{1: 10},
+			current:  map[int]uint64{1: 10},
+			elapsed:  time.Second,
+			expected: 0,
+		},
+		{
+			name:     "single process",
+			previous: map[int]uint64{1: 10},
+			current:  map[int]uint64{1: 60},
+			elapsed:  time.Second,
+			expected: 50,
+		},
+		{
+			name:     "multiple cpus",
+			previous: map[int]uint64{1: 0, 2: 0},
+			current:  map[int]uint64{1: 100, 2: 50},
+			elapsed:  500 * time.Millisecond,
+			expected: 300,
+		},
+		{
+			name:     "new process",
+			previous: map[int]uint64{1: 10},
+			current:  map[int]uint64{1: 20, 2: 5},
+			elapsed:  time.Second,
+			expected: 15,
+		},
+		{
+			name:     "exited process",
+			previous: map[int]uint64{1: 10, 2: 40},
+			current:  map[int]uint64{1: 20},
+			elapsed:  time.Second,
+			expected: 10,
+		},
+		{
+			name:     "reused pid",
+			previous: map[int]uint64{1: 40},
+			current:  map[int]uint64{1: 5},
+			elapsed:  time.Second,
+			expected: 0,
+		},
+		{
+			name:     "rounded",
+			previous: map[int]uint64{1: 0},
+			current:  map[int]uint64{1: 1},
+			elapsed:  3 * time.Second,
+			expected: 0.33,
+		},
+		{
+			name:     "no time elapsed",
+			previous: map[int]uint64{1: 0},
+			current:  map[int]uint64{1: 10},
+			elapsed:  0,
+			expected: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := processSample{cpuTicks: tt.previous}
+			current := processSample{cpuTicks: tt.current}
+			if cpu := cpuPercent(previous, current, tt.elapsed); cpu != tt.expected {
+				t.Errorf("cpuPercent(%v, %v, %s) = %v, expected %v", tt.previous, tt.current, tt.elapsed, cpu,
+					tt.expected)
+			}
+		})
+	}
+}
+
+// TestReadProcessTree checks that the resource usage of the test process is read from /proc.
+func TestReadProcessTree(t *testing.T) {
+	sample, err := readProcessTree(os.Getpid())
+	if err != nil {
+		t.Fatalf("readProcessTree(%d) = %v, expected nil", os.Getpid(), err)
+	}
+	if sample.processes < 1 || sample.threads < 1 || sample.rssBytes <= 0 || sample.openFDs < 3 {
+		t.Errorf("unexpected sample: %+v", sample)
+	}
+	if _, ok := sample.cpuTicks[os.Getpid()]; !ok {
+		t.Errorf("cpuTicks = %v, expected an entry for %d", sample.cpuTicks, os.Getpid())
+	}
+
+	if _, err := readProcessTree(-1); err == nil {
+		t.Errorf("readProcessTree(-1) = nil, expected an error")
+	}
+}
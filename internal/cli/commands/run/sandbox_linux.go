@@ -0,0 +1,326 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/json-exec/internal/errors"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// capability which allows json-exec to create namespaces without a user namespace
+const capSysAdmin = 21
+
+// oPath opens a file descriptor which only refers to a location in the filesystem, which is missing from the syscall
+// package on some architectures.
+const oPath = 0x200000
+
+// _sandboxNamespaces is the list of namespaces created for the sandbox.
+var _sandboxNamespaces = []struct {
+	flag uintptr
+	name string
+}{
+	{flag: syscall.CLONE_NEWUSER, name: "user"},
+	{flag: syscall.CLONE_NEWNS, name: "mount"},
+	{flag: syscall.CLONE_NEWPID, name: "pid"},
+	{flag: syscall.CLONE_NEWNET, name: "net"},
+	{flag: syscall.CLONE_NEWIPC, name: "ipc"},
+}
+
+// sandbox isolates the command using namespaces.
+//
+// The namespaces are created when the command is forked. Since mounts can only be changed from within the new mount
+// namespace, the helper makes them before executing the program. When json-exec is not privileged, a user namespace
+// is created as well in which the command runs as root, mapped to the user and group of json-exec.
+type sandbox struct {
+	// unexported members
+	cfg           *config.SandboxConfig
+	flags         uintptr
+	gid           int
+	namespaces    []string
+	uid           int
+	userNamespace bool
+}
+
+// newSandbox determines how the command is isolated.
+//
+// ctx contains the effective user and groups of the command, which cannot be changed by an unprivileged sandbox.
+func newSandbox(cfg *config.SandboxConfig, ctx *executionContext) (*sandbox, error) {
+	s := &sandbox{
+		cfg:           cfg,
+		gid:           os.Getegid(),
+		namespaces:    []string{},
+		uid:           os.Geteuid(),
+		userNamespace: !hasCapability(capSysAdmin),
+	}
+	if s.userNamespace {
+		if ctx.credentialChanged {
+			return nil, &settingError{setting: "sandbox", err: fmt.Errorf(
+				"an unprivileged sandbox cannot run the command as a different user or group")}
+		}
+		if data, err := os.ReadFile("/proc/sys/user/max_user_namespaces"); err == nil && string(data) == "0\n" {
+			return nil, &settingError{setting: "sandbox", err: fmt.Errorf(
+				"user namespaces are disabled and json-exec does not have the CAP_SYS_ADMIN capability")}
+		}
+	}
+	for _, bind := range cfg.Binds {
+		if _, err := os.Stat(bind.Source); err != nil {
+			return nil, &settingError{setting: "sandbox read-only path " + bind.Target, err: err}
+		}
+	}
+	for _, ns := range _sandboxNamespaces {
+		switch {
+		case ns.flag == syscall.CLONE_NEWUSER && !s.userNamespace:
+			continue
+		case ns.flag == syscall.CLONE_NEWNET && cfg.Network:
+			continue
+		}
+		s.flags |= ns.flag
+		s.namespaces = append(s.namespaces, ns.name)
+	}
+	return s, nil
+}
+
+// Attach configures the command to start in the namespaces of the sandbox.
+func (s *sandbox) Attach(command *exec.Cmd) {
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Cloneflags |= s.flags
+	if s.userNamespace {
+		command.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: s.uid, Size: 1}}
+		command.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: s.gid, Size: 1}}
+		command.SysProcAttr.GidMappingsEnableSetgroups = false
+	}
+}
+
+// HelperSpec returns the settings the helper applies inside the namespaces of the sandbox.
+func (s *sandbox) HelperSpec() *helperSandbox {
+	spec := &helperSandbox{
+		Binds:      make([]helperBind, 0, len(s.cfg.Binds)),
+		Loopback:   !s.cfg.Network,
+		MountProc:  true,
+		PrivateTmp: s.cfg.PrivateTmp,
+	}
+	for _, bind := range s.cfg.Binds {
+		spec.Binds = append(spec.Binds, helperBind{Source: bind.Source, Target: bind.Target})
+	}
+	return spec
+}
+
+// AddFields adds the isolation applied to the command to the given logging context.
+func (s *sandbox) AddFields(ctx zerolog.Context) zerolog.Context {
+	readOnly := make([]string, 0, len(s.cfg.Binds))
+	for _, bind := range s.cfg.Binds {
+		if bind.Source == bind.Target {
+			readOnly = append(readOnly, bind.Target)
+		} else {
+			readOnly = append(readOnly, bind.Source+":"+bind.Target)
+		}
+	}
+	return ctx.Dict("sandbox", zerolog.Dict().
+		Strs("namespaces", s.namespaces).
+		Bool("network", s.cfg.Network).
+		Bool("private_tmp", s.cfg.PrivateTmp).
+		Strs("read_only", readOnly).
+		Bool("user_namespace", s.userNamespace))
+}
+
+// setupSandbox makes the mounts of the sandbox and brings up the loopback interface.
+//
+// This function is called by the helper inside the namespaces of the sandbox. The name of the setting which failed
+// is returned along with the error.
+func setupSandbox(spec *helperSandbox) (string, error) {
+	// keep mounts made in the sandbox from propagating to the host
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return "sandbox mounts", err
+	}
+
+	// open the sources of the bind mounts before anything is mounted over them, such as a private /tmp
+	sources := make([]int, 0, len(spec.Binds))
+	for _, bind := range spec.Binds {
+		fd, err := syscall.Open(bind.Source, oPath|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return "sandbox read-only path " + bind.Target, err
+		}
+		sources = append(sources, fd)
+	}
+
+	if spec.MountProc {
+		if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC,
+			""); err != nil {
+			return "sandbox /proc", err
+		}
+	}
+	if spec.PrivateTmp {
+		if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return "sandbox /tmp", err
+		}
+	}
+	for i, bind := range spec.Binds {
+		if spec.PrivateTmp && strings.HasPrefix(bind.Target, "/tmp/") {
+			if err := createMountPoint(sources[i], bind.Target); err != nil {
+				return "sandbox read-only path " + bind.Target, err
+			}
+		}
+		if err := mountReadOnly(fmt.Sprintf("/proc/self/fd/%d", sources[i]), bind.Target); err != nil {
+			return "sandbox read-only path " + bind.Target, err
+		}
+		syscall.Close(sources[i])
+	}
+	if spec.Loopback {
+		if err := bringUpLoopback(); err != nil {
+			return "sandbox loopback", err
+		}
+	}
+
+	// the working directory may have been mounted over
+	if wd, err := os.Getwd(); err == nil {
+		if err := syscall.Chdir(wd); err != nil {
+			return settingWorkingDirectory, fmt.Errorf("%s is not available in the sandbox: %s", wd, err.Error())
+		}
+	}
+	return "", nil
+}
+
+// runSandboxInit starts the program in a child process and stays behind as the init process of the PID namespace.
+//
+// The init process of a PID namespace does not receive signals it does not handle, such as TERM sent by a timeout,
+// and must reap orphaned processes, neither of which most programs do. The child executes the helper again without
+// the sandbox settings so that the remaining settings are applied to the program itself, while the init process
+// discards any signal it receives and reaps every process until the program exits. It then exits with the exit code
+// of the program, or with the exit code a shell would report if the program was terminated by a signal, in which case
+// the signal is also written to the status file descriptor.
+//
+// This function is called by the helper inside the namespaces of the sandbox and only returns if the program could
+// not be started.
+func runSandboxInit(spec *helperSpec, report *os.File, args []string) error {
+	// the child must not release a hold or set up the sandbox again, but inherits the descriptors which come before
+	// the report, such as the listener of the seccomp filter and a pinned binary, under the same numbers
+	child := *spec
+	child.HoldFD = 0
+	child.Sandbox = nil
+	child.StatusFD = 0
+	files := make([]uintptr, 0, spec.ReportFD+1)
+	for fd := 0; fd <= spec.ReportFD; fd++ {
+		files = append(files, uintptr(fd))
+	}
+	data, err := json.Marshal(child)
+	if err != nil { // should never happen
+		return err
+	}
+
+	// signals are caught rather than ignored since ignored signals would stay ignored in the program
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals)
+	go func() {
+		for range signals {
+		}
+	}()
+
+	pid, err := syscall.ForkExec("/proc/self/exe", append([]string{os.Args[0], HelperArg, string(data)}, args[1:]...),
+		&syscall.ProcAttr{Env: os.Environ(), Files: files})
+	if err != nil {
+		return err
+	}
+	report.Close()
+	if spec.Seccomp != nil && spec.Seccomp.ListenerFD > 0 {
+		syscall.Close(spec.Seccomp.ListenerFD)
+	}
+
+	for {
+		var ws syscall.WaitStatus
+		wpid, err := syscall.Wait4(-1, &ws, 0, nil)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			os.Exit(errors.GeneralFailure)
+		}
+		if wpid != pid {
+			continue
+		}
+		if ws.Signaled() {
+			if spec.StatusFD > 0 {
+				data, _ := json.Marshal(helperStatus{CoreDumped: ws.CoreDump(), Signal: int(ws.Signal())})
+				syscall.Write(spec.StatusFD, data)
+			}
+			os.Exit(errors.SignalBase + int(ws.Signal()))
+		}
+		os.Exit(ws.ExitStatus())
+	}
+}
+
+// createMountPoint creates the target of a bind mount within the private /tmp if it does not exist.
+//
+// The target is created as a directory or an empty file depending on the type of the source.
+func createMountPoint(source int, target string) error {
+	if _, err := os.Lstat(target); err == nil {
+		return nil
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(source, &st); err != nil {
+		return err
+	}
+	if st.Mode&syscall.S_IFMT == syscall.S_IFDIR {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// mountReadOnly bind mounts the source at the target and makes it read-only.
+//
+// Remounting a bind mount which belongs to a more privileged user namespace fails unless the flags which are locked
+// by that namespace are kept, so they are copied from the source.
+func mountReadOnly(source, target string) error {
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(target, &st); err != nil {
+		return err
+	}
+	locked := uintptr(st.Flags) & (syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME |
+		syscall.MS_NODIRATIME | syscall.MS_RELATIME)
+	return syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|locked, "")
+}
+
+// ifreqFlags is the ifreq structure used to get and set the flags of a network interface.
+//
+// The structure is padded to the size of the largest member of the union on 64-bit systems.
+type ifreqFlags struct {
+	name  [syscall.IFNAMSIZ]byte
+	flags uint16
+	_     [22]byte
+}
+
+// bringUpLoopback brings up the loopback interface, which is down in a new network namespace.
+func bringUpLoopback() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var req ifreqFlags
+	copy(req.name[:], "lo")
+	if err := ioctl(uintptr(fd), syscall.SIOCGIFFLAGS, unsafe.Pointer(&req)); err != nil {
+		return err
+	}
+	req.flags |= syscall.IFF_UP
+	return ioctl(uintptr(fd), syscall.SIOCSIFFLAGS, unsafe.Pointer(&req))
+}
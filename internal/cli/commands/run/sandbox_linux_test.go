@@ -0,0 +1,37 @@
+package run
+
+import (
+	"os"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestNewSandboxBinds checks that the sources of read-only paths must exist when the sandbox is created.
+func TestNewSandboxBinds(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		binds   []config.SandboxBind
+		setting string
+	}{
+		{name: "existing source", binds: []config.SandboxBind{{Source: dir, Target: "/mnt/data"}}},
+		{name: "missing source", binds: []config.SandboxBind{{Source: dir + "/missing", Target: "/mnt/data"}},
+			setting: "sandbox read-only path /mnt/data"},
+	}
+	for _, tt := range tests {
+		_, err := newSandbox(&config.SandboxConfig{Enabled: true, Binds: tt.binds}, &executionContext{})
+		if serr, ok := err.(*settingError); ok && serr.setting == "sandbox" {
+			t.Skipf("sandbox is not available: %s", err.Error())
+		}
+		switch {
+		case tt.setting == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", tt.name, err.Error())
+		case tt.setting != "":
+			serr, ok := err.(*settingError)
+			if !ok || serr.setting != tt.setting || !os.IsNotExist(serr.err) {
+				t.Errorf("%s: newSandbox() = %v, expected %s error", tt.name, err, tt.setting)
+			}
+		}
+	}
+}
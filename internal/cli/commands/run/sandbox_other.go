@@ -0,0 +1,44 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// sandbox is not supported on this platform.
+type sandbox struct{}
+
+// newSandbox always returns an error since namespaces are only supported on Linux.
+func newSandbox(cfg *config.SandboxConfig, ctx *executionContext) (*sandbox, error) {
+	return nil, &settingError{setting: "sandbox", err: fmt.Errorf("sandboxing is only supported on Linux")}
+}
+
+// Attach does nothing.
+func (s *sandbox) Attach(command *exec.Cmd) {}
+
+// HelperSpec always returns nil.
+func (s *sandbox) HelperSpec() *helperSandbox {
+	return nil
+}
+
+// AddFields does nothing.
+func (s *sandbox) AddFields(ctx zerolog.Context) zerolog.Context {
+	return ctx
+}
+
+// setupSandbox always returns an error since namespaces are only supported on Linux.
+func setupSandbox(spec *helperSandbox) (string, error) {
+	return "sandbox", fmt.Errorf("sandboxing is only supported on Linux")
+}
+
+// runSandboxInit always returns an error since namespaces are only supported on Linux.
+func runSandboxInit(spec *helperSpec, report *os.File, args []string) error {
+	return fmt.Errorf("sandboxing is only supported on Linux")
+}
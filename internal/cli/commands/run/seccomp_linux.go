@@ -0,0 +1,486 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"syscall"
+	"unsafe"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/json-exec/internal/errors"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// seccomp operations, flags, return values and ioctls, which are missing from the syscall package.
+const (
+	seccompSetModeFilter         = 1
+	seccompFilterFlagNewListener = 1 << 3
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetUserNotif   = 0x7fc00000
+	seccompRetAllow       = 0x7fff0000
+
+	seccompIoctlNotifRecv = 0xc0502100
+	seccompIoctlNotifSend = 0xc0182101
+)
+
+// offsets of the fields of the seccomp_data structure checked by the filter
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArg0 = 16
+)
+
+// seccompX32Bit is set in the numbers of system calls made using the x32 ABI, which are always denied.
+const seccompX32Bit = 0x40000000
+
+// poll events, which are missing from the syscall package
+const (
+	pollIn  = 0x1
+	pollErr = 0x8
+	pollHup = 0x10
+)
+
+// _seccompRequiredSyscalls is the list of system calls which are always allowed since the helper may need them
+// between installing the filter and executing the program.
+var _seccompRequiredSyscalls = []string{"execve", "exit", "exit_group", "futex", "rt_sigreturn", "sendmsg"}
+
+// _seccompProfiles maps each built-in profile to the system calls it denies.
+var _seccompProfiles = map[string][]seccompRule{
+	config.SeccompProfileNoNetwork: {
+		{name: "socket", arg0: []uint32{syscall.AF_UNIX}},
+	},
+	config.SeccompProfileNoPtrace: {
+		{name: "pidfd_getfd"},
+		{name: "process_vm_readv"},
+		{name: "process_vm_writev"},
+		{name: "ptrace"},
+	},
+}
+
+// seccompRule denies a system call unless its first argument is one of the given values.
+type seccompRule struct {
+	arg0 []uint32
+	name string
+}
+
+// seccompNotif is the seccomp_notif structure.
+type seccompNotif struct {
+	id    uint64
+	pid   uint32
+	flags uint32
+	nr    int32
+	arch  uint32
+	ip    uint64
+	args  [6]uint64
+}
+
+// seccompNotifResp is the seccomp_notif_resp structure.
+type seccompNotifResp struct {
+	id    uint64
+	val   int64
+	error int32
+	flags uint32
+}
+
+// pollFd is the pollfd structure.
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+// seccompFilter filters the system calls the command may make.
+//
+// A seccomp filter can only be installed by a process on itself, so the helper installs it right before executing
+// the program. When system calls which are not allowed fail with EPERM, the filter reports them to a listener which
+// the helper sends back to json-exec so that the denied system calls can be recorded in the result.
+type seccompFilter struct {
+	// unexported members
+	cfg        *config.SeccompConfig
+	names      map[int32]string
+	program    []helperInstruction
+	receiver   *os.File
+	sender     *os.File
+	stop       *os.File
+	stopped    chan struct{}
+	violations map[string]int
+}
+
+// newSeccompFilter builds the seccomp filter from the configured system calls.
+func newSeccompFilter(cfg *config.SeccompConfig) (*seccompFilter, error) {
+	if seccompAuditArch == 0 {
+		return nil, &settingError{setting: "seccomp filter", err: fmt.Errorf(
+			"seccomp filters are not supported on this architecture")}
+	}
+	lookup := func(name string) (uint32, error) {
+		nr, ok := _seccompSyscalls[name]
+		if !ok {
+			return 0, &settingError{setting: "seccomp filter", err: fmt.Errorf("unknown system call '%s'", name)}
+		}
+		return uint32(nr), nil
+	}
+
+	// determine the system calls which are denied, with the arguments they are still allowed with
+	required := map[uint32]bool{}
+	for _, name := range _seccompRequiredSyscalls {
+		nr, _ := lookup(name)
+		required[nr] = true
+	}
+	denied := map[uint32][]uint32{}
+	deny := func(rule seccompRule) error {
+		nr, err := lookup(rule.name)
+		if err != nil {
+			return err
+		}
+		if required[nr] {
+			return &settingError{setting: "seccomp filter", err: fmt.Errorf(
+				"system call '%s' is required to execute the command and cannot be denied", rule.name)}
+		}
+		if arg0, ok := denied[nr]; ok && (arg0 == nil || rule.arg0 != nil) {
+			return nil
+		}
+		denied[nr] = rule.arg0
+		return nil
+	}
+	for _, profile := range cfg.Profiles {
+		for _, rule := range _seccompProfiles[profile] {
+			if err := deny(rule); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range cfg.Deny {
+		if err := deny(seccompRule{name: name}); err != nil {
+			return nil, err
+		}
+	}
+	allowed := map[uint32]bool{}
+	for _, name := range cfg.Allow {
+		nr, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		allowed[nr] = true
+	}
+
+	// denied system calls are reported to the listener when they fail with EPERM
+	action := uint32(seccompRetUserNotif)
+	if cfg.Action == config.SeccompActionKill {
+		action = seccompRetKillProcess
+	}
+	f := &seccompFilter{
+		cfg:        cfg,
+		names:      map[int32]string{},
+		violations: map[string]int{},
+	}
+	for name, nr := range _seccompSyscalls {
+		f.names[int32(nr)] = name
+	}
+
+	// check the architecture and system call number before any of the rules
+	f.program = []helperInstruction{
+		bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataArch),
+		bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, seccompAuditArch, 1, 0),
+		bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetKillProcess),
+		bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataNr),
+		bpfJump(syscall.BPF_JMP|syscall.BPF_JSET|syscall.BPF_K, seccompX32Bit, 0, 1),
+		bpfStmt(syscall.BPF_RET|syscall.BPF_K, action),
+	}
+	for _, nr := range sortedSyscalls(required) {
+		f.program = append(f.program,
+			bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, nr, 0, 1),
+			bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow))
+	}
+	deniedSet := map[uint32]bool{}
+	for nr := range denied {
+		deniedSet[nr] = true
+	}
+	for _, nr := range sortedSyscalls(deniedSet) {
+		arg0 := denied[nr]
+		if arg0 == nil {
+			f.program = append(f.program,
+				bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, nr, 0, 1),
+				bpfStmt(syscall.BPF_RET|syscall.BPF_K, action))
+			continue
+		}
+		f.program = append(f.program,
+			bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, nr, 0, uint8(len(arg0)+3)),
+			bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataArg0))
+		for i, value := range arg0 {
+			f.program = append(f.program,
+				bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, value, uint8(len(arg0)-i), 0))
+		}
+		f.program = append(f.program,
+			bpfStmt(syscall.BPF_RET|syscall.BPF_K, action),
+			bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow))
+	}
+	if len(allowed) == 0 {
+		f.program = append(f.program, bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow))
+		return f, nil
+	}
+	for _, nr := range sortedSyscalls(allowed) {
+		f.program = append(f.program,
+			bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, nr, 0, 1),
+			bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow))
+	}
+	f.program = append(f.program, bpfStmt(syscall.BPF_RET|syscall.BPF_K, action))
+	return f, nil
+}
+
+// Attach creates the socket over which the helper sends the listener of the filter and returns the settings of the
+// filter for the helper.
+func (f *seccompFilter) Attach(command *exec.Cmd) (*helperSeccomp, error) {
+	spec := &helperSeccomp{Filter: f.program}
+	if f.cfg.Action != config.SeccompActionErrno {
+		return spec, nil
+	}
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create seccomp socket: %s", err.Error())
+	}
+	f.receiver = os.NewFile(uintptr(fds[0]), "seccomp")
+	f.sender = os.NewFile(uintptr(fds[1]), "seccomp")
+	spec.ListenerFD = 3 + len(command.ExtraFiles)
+	command.ExtraFiles = append(command.ExtraFiles, f.sender)
+	return spec, nil
+}
+
+// Listen starts recording the system calls denied by the filter once the helper has been started.
+//
+// Since the helper itself may make system calls which are not allowed after installing the filter, the listener must
+// be received before waiting for the helper to execute the program.
+func (f *seccompFilter) Listen() {
+	if f.receiver == nil {
+		return
+	}
+	f.sender.Close()
+	f.sender = nil
+	wake, stop, err := os.Pipe()
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to create seccomp pipe, denied system calls will not be recorded: %s",
+			err.Error())
+		f.receiver.Close()
+		f.receiver = nil
+		return
+	}
+	f.stop = stop
+	f.stopped = make(chan struct{})
+	go f.receive(f.receiver, wake)
+	f.receiver = nil
+}
+
+// receive receives the listener from the helper and records denied system calls until the filter is stopped.
+func (f *seccompFilter) receive(receiver *os.File, wake *os.File) {
+	defer close(f.stopped)
+	defer wake.Close()
+	defer receiver.Close()
+
+	// the socket is closed without any message if the helper fails before installing the filter
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := syscall.Recvmsg(int(receiver.Fd()), make([]byte, 1), oob, 0)
+	if err != nil || n == 0 {
+		return
+	}
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(messages) == 0 {
+		return
+	}
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	if err != nil || len(fds) == 0 {
+		return
+	}
+	listener := fds[0]
+	defer syscall.Close(listener)
+
+	pollFds := []pollFd{
+		{fd: int32(listener), events: pollIn},
+		{fd: int32(wake.Fd()), events: pollIn},
+	}
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&pollFds[0])),
+			uintptr(len(pollFds)), 0, 0, 0, 0)
+		switch {
+		case errno == syscall.EINTR:
+			continue
+		case errno != 0, pollFds[1].revents != 0:
+			return
+		case pollFds[0].revents&pollIn != 0:
+			f.deny(listener)
+		case pollFds[0].revents&(pollErr|pollHup) != 0:
+			return // no process uses the filter anymore
+		}
+	}
+}
+
+// deny fails the next system call reported to the listener with EPERM and records it.
+func (f *seccompFilter) deny(listener int) {
+	var notif seccompNotif
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(listener), seccompIoctlNotifRecv,
+		uintptr(unsafe.Pointer(&notif))); errno != 0 {
+		return // the process was killed before the notification was received
+	}
+	resp := seccompNotifResp{
+		id:    notif.id,
+		error: -int32(syscall.EPERM),
+	}
+	syscall.Syscall(syscall.SYS_IOCTL, uintptr(listener), seccompIoctlNotifSend, uintptr(unsafe.Pointer(&resp)))
+
+	name, ok := f.names[notif.nr]
+	if !ok {
+		name = fmt.Sprintf("%d", notif.nr)
+	}
+	f.violations[name]++
+}
+
+// Stop stops recording denied system calls.
+//
+// This function must be called once the command has exited or failed to start.
+func (f *seccompFilter) Stop() {
+	if f.sender != nil {
+		f.sender.Close()
+		f.sender = nil
+	}
+	if f.receiver != nil {
+		f.receiver.Close()
+		f.receiver = nil
+	}
+	if f.stopped != nil {
+		f.stop.Close()
+		<-f.stopped
+		f.stopped = nil
+	}
+}
+
+// Killed returns whether or not the command was killed for making a system call which is not allowed.
+func (f *seccompFilter) Killed(state *os.ProcessState, status exitStatus) bool {
+	if state == nil || f.cfg.Action != config.SeccompActionKill {
+		return false
+	}
+	sig := status.signal
+	if !status.signaled && state.ExitCode() > errors.SignalBase {
+		sig = syscall.Signal(state.ExitCode() - errors.SignalBase)
+	}
+	return sig == syscall.SIGSYS
+}
+
+// AddFields adds the system calls the command may make to the given logging context.
+func (f *seccompFilter) AddFields(ctx zerolog.Context) zerolog.Context {
+	return ctx.Dict("seccomp", zerolog.Dict().
+		Str("action", f.cfg.Action).
+		Strs("allow", f.cfg.Allow).
+		Strs("deny", f.cfg.Deny).
+		Strs("profiles", f.cfg.Profiles))
+}
+
+// AddResultFields adds the system calls which were denied to the given logging context.
+//
+// killed indicates whether or not the command was killed for making a system call which is not allowed.
+func (f *seccompFilter) AddResultFields(ctx zerolog.Context, killed bool) zerolog.Context {
+	ctx = ctx.Bool("seccomp_violation", killed || len(f.violations) > 0)
+	if len(f.violations) == 0 {
+		return ctx
+	}
+	names := make([]string, 0, len(f.violations))
+	for name := range f.violations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	violations := zerolog.Dict()
+	for _, name := range names {
+		violations = violations.Int(name, f.violations[name])
+	}
+	return ctx.Dict("seccomp_violations", violations)
+}
+
+// execWithSeccomp installs the seccomp filter on the current thread, which must not be able to gain privileges, and
+// executes the program.
+//
+// This function is called by the helper once everything else is applied and only returns if the program could not be
+// executed, along with the name of the setting which failed, if any. Since the filter may deny the system calls the Go
+// runtime makes when allocating memory, everything is allocated before the filter is installed and the program is
+// executed with a raw system call rather than syscall.Exec.
+func execWithSeccomp(spec *helperSeccomp, path string, argv, envv []string) (string, error) {
+	pathp, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	argvp, err := syscall.SlicePtrFromStrings(argv)
+	if err != nil {
+		return "", err
+	}
+	envvp, err := syscall.SlicePtrFromStrings(envv)
+	if err != nil {
+		return "", err
+	}
+	filter := make([]syscall.SockFilter, len(spec.Filter))
+	for i, ins := range spec.Filter {
+		filter[i] = syscall.SockFilter{Code: ins.Code, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	prog := syscall.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	message := []byte{0}
+	iov := syscall.Iovec{Base: &message[0]}
+	iov.SetLen(len(message))
+	rights := syscall.UnixRights(0)
+	msg := syscall.Msghdr{Iov: &iov, Control: &rights[0]}
+	msg.Iovlen = 1
+	msg.SetControllen(len(rights))
+	var flags uintptr
+	if spec.ListenerFD > 0 {
+		flags = seccompFilterFlagNewListener
+	}
+	listener, _, errno := syscall.RawSyscall(sysSeccomp, seccompSetModeFilter, flags, uintptr(unsafe.Pointer(&prog)))
+	if (errno == syscall.EINVAL || errno == syscall.EMFILE) && flags != 0 {
+		// the kernel does not support listeners, or the open files limit of the command leaves no room for one, so
+		// system calls are denied without being recorded
+		for i := range filter {
+			if filter[i].Code == syscall.BPF_RET|syscall.BPF_K && filter[i].K == seccompRetUserNotif {
+				filter[i].K = seccompRetErrno | uint32(syscall.EPERM)
+			}
+		}
+		flags = 0
+		listener, _, errno = syscall.RawSyscall(sysSeccomp, seccompSetModeFilter, flags,
+			uintptr(unsafe.Pointer(&prog)))
+	}
+	if errno != 0 {
+		return "seccomp filter", errno
+	}
+	if flags != 0 {
+		// the descriptor follows the header of the control message
+		*(*int32)(unsafe.Pointer(&rights[syscall.CmsgLen(0)])) = int32(listener)
+		if _, _, errno := syscall.RawSyscall(sysSendmsg, uintptr(spec.ListenerFD),
+			uintptr(unsafe.Pointer(&msg)), 0); errno != 0 {
+			return "seccomp filter", errno
+		}
+	}
+	_, _, errno = syscall.RawSyscall(syscall.SYS_EXECVE, uintptr(unsafe.Pointer(pathp)),
+		uintptr(unsafe.Pointer(&argvp[0])), uintptr(unsafe.Pointer(&envvp[0])))
+	return "", errno
+}
+
+// bpfStmt returns a BPF instruction without any jumps.
+func bpfStmt(code uint16, k uint32) helperInstruction {
+	return helperInstruction{Code: code, K: k}
+}
+
+// bpfJump returns a conditional BPF jump instruction.
+func bpfJump(code uint16, k uint32, jt, jf uint8) helperInstruction {
+	return helperInstruction{Code: code, Jf: jf, Jt: jt, K: k}
+}
+
+// sortedSyscalls returns the system call numbers in the given set in ascending order.
+func sortedSyscalls(set map[uint32]bool) []uint32 {
+	nrs := make([]uint32, 0, len(set))
+	for nr := range set {
+		nrs = append(nrs, nr)
+	}
+	sort.Slice(nrs, func(i, j int) bool { return nrs[i] < nrs[j] })
+	return nrs
+}
@@ -0,0 +1,16 @@
+//go:build linux && !amd64 && !arm64
+// +build linux,!amd64,!arm64
+
+package run
+
+// seccompAuditArch is 0 since seccomp filters are not supported on this architecture.
+const seccompAuditArch = 0
+
+// sysSeccomp is 0 since seccomp filters are not supported on this architecture.
+const sysSeccomp = 0
+
+// sysSendmsg is 0 since seccomp filters are not supported on this architecture.
+const sysSendmsg = 0
+
+// _seccompSyscalls is empty since seccomp filters are not supported on this architecture.
+var _seccompSyscalls = map[string]int{}
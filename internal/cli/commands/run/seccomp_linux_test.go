@@ -0,0 +1,158 @@
+package run
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// seccompCall is a system call checked by a seccomp filter.
+type seccompCall struct {
+	// unexported members
+	arch uint32
+	arg0 uint32
+	name string
+	nr   uint32
+}
+
+// runSeccompFilter runs the BPF program of the filter against the system call and returns the action.
+//
+// Only the instructions generated by newSeccompFilter are supported.
+func runSeccompFilter(t *testing.T, program []helperInstruction, call seccompCall) uint32 {
+	nr := call.nr
+	if call.name != "" {
+		nr |= uint32(_seccompSyscalls[call.name])
+	}
+	arch := call.arch
+	if arch == 0 {
+		arch = seccompAuditArch
+	}
+	var acc uint32
+	for pc := 0; pc < len(program); pc++ {
+		ins := program[pc]
+		switch ins.Code {
+		case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS:
+			switch ins.K {
+			case seccompDataNr:
+				acc = nr
+			case seccompDataArch:
+				acc = arch
+			case seccompDataArg0:
+				acc = call.arg0
+			default:
+				t.Fatalf("unexpected load offset %d", ins.K)
+			}
+		case syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K:
+			if acc == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_K:
+			if acc&ins.K != 0 {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case syscall.BPF_RET | syscall.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("unexpected instruction %#x", ins.Code)
+		}
+	}
+	t.Fatalf("program ended without returning")
+	return 0
+}
+
+// TestNewSeccompFilter checks that the BPF program built for the filter allows and denies the expected system calls.
+func TestNewSeccompFilter(t *testing.T) {
+	if seccompAuditArch == 0 {
+		if _, err := newSeccompFilter(&config.SeccompConfig{}); err == nil {
+			t.Errorf("newSeccompFilter() succeeded on an unsupported architecture")
+		}
+		t.Skip("seccomp filters are not supported on this architecture")
+	}
+
+	const notify, kill, allow = seccompRetUserNotif, seccompRetKillProcess, seccompRetAllow
+	tests := []struct {
+		name  string
+		cfg   config.SeccompConfig
+		calls map[uint32][]seccompCall
+	}{
+		{
+			name: "no network",
+			cfg:  config.SeccompConfig{Action: config.SeccompActionErrno, Profiles: []string{"no-network"}},
+			calls: map[uint32][]seccompCall{
+				notify: {{name: "socket", arg0: syscall.AF_INET}, {nr: seccompX32Bit | 1}},
+				allow:  {{name: "socket", arg0: syscall.AF_UNIX}, {name: "read"}, {name: "connect"}},
+				kill:   {{name: "read", arch: 0x40000003}},
+			},
+		},
+		{
+			name: "denied with kill",
+			cfg: config.SeccompConfig{Action: config.SeccompActionKill, Profiles: []string{"no-ptrace"},
+				Deny: []string{"mount"}},
+			calls: map[uint32][]seccompCall{
+				kill:  {{name: "ptrace"}, {name: "process_vm_readv"}, {name: "mount"}},
+				allow: {{name: "execve"}, {name: "openat"}},
+			},
+		},
+		{
+			name: "denied without arguments",
+			cfg: config.SeccompConfig{Action: config.SeccompActionErrno, Profiles: []string{"no-network"},
+				Deny: []string{"socket"}},
+			calls: map[uint32][]seccompCall{
+				notify: {{name: "socket", arg0: syscall.AF_UNIX}, {name: "socket", arg0: syscall.AF_INET}},
+				allow:  {{name: "read"}},
+			},
+		},
+		{
+			name: "allowed",
+			cfg:  config.SeccompConfig{Action: config.SeccompActionErrno, Allow: []string{"read", "write"}},
+			calls: map[uint32][]seccompCall{
+				allow:  {{name: "read"}, {name: "write"}, {name: "execve"}, {name: "exit_group"}},
+				notify: {{name: "openat"}, {name: "socket", arg0: syscall.AF_UNIX}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		f, err := newSeccompFilter(&tt.cfg)
+		if err != nil {
+			t.Errorf("%s: newSeccompFilter() failed: %s", tt.name, err.Error())
+			continue
+		}
+		for want, calls := range tt.calls {
+			for _, call := range calls {
+				if got := runSeccompFilter(t, f.program, call); got != want {
+					t.Errorf("%s: filter returned %#x for %+v, expected %#x", tt.name, got, call, want)
+				}
+			}
+		}
+	}
+}
+
+// TestNewSeccompFilterErrors checks that unknown and required system calls are rejected.
+func TestNewSeccompFilterErrors(t *testing.T) {
+	if seccompAuditArch == 0 {
+		t.Skip("seccomp filters are not supported on this architecture")
+	}
+	tests := []struct {
+		name string
+		cfg  config.SeccompConfig
+		err  string
+	}{
+		{name: "unknown denied", cfg: config.SeccompConfig{Deny: []string{"nope"}}, err: "unknown system call 'nope'"},
+		{name: "unknown allowed", cfg: config.SeccompConfig{Allow: []string{"nope"}}, err: "unknown system call"},
+		{name: "required", cfg: config.SeccompConfig{Deny: []string{"execve"}},
+			err: "system call 'execve' is required to execute the command"},
+	}
+	for _, tt := range tests {
+		_, err := newSeccompFilter(&tt.cfg)
+		serr, ok := err.(*settingError)
+		if !ok || serr.setting != "seccomp filter" || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: newSeccompFilter() = %v, expected error containing '%s'", tt.name, err, tt.err)
+		}
+	}
+}
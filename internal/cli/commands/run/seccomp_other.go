@@ -0,0 +1,52 @@
+//go:build !linux
+// +build !linux
+
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// seccompFilter is not supported on this platform.
+type seccompFilter struct{}
+
+// newSeccompFilter always returns an error since seccomp is only supported on Linux.
+func newSeccompFilter(cfg *config.SeccompConfig) (*seccompFilter, error) {
+	return nil, &settingError{setting: "seccomp filter", err: fmt.Errorf("seccomp filters are only supported on Linux")}
+}
+
+// Attach always returns nil.
+func (f *seccompFilter) Attach(command *exec.Cmd) (*helperSeccomp, error) {
+	return nil, nil
+}
+
+// Listen does nothing.
+func (f *seccompFilter) Listen() {}
+
+// Stop does nothing.
+func (f *seccompFilter) Stop() {}
+
+// Killed always returns false.
+func (f *seccompFilter) Killed(state *os.ProcessState, status exitStatus) bool {
+	return false
+}
+
+// AddFields does nothing.
+func (f *seccompFilter) AddFields(ctx zerolog.Context) zerolog.Context {
+	return ctx
+}
+
+// AddResultFields does nothing.
+func (f *seccompFilter) AddResultFields(ctx zerolog.Context, killed bool) zerolog.Context {
+	return ctx
+}
+
+// execWithSeccomp always returns an error since seccomp is only supported on Linux.
+func execWithSeccomp(spec *helperSeccomp, path string, argv, envv []string) (string, error) {
+	return "seccomp filter", fmt.Errorf("seccomp filters are only supported on Linux")
+}
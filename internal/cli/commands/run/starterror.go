@@ -0,0 +1,60 @@
+package run
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Types of errors which prevent a command from starting.
+const (
+	startErrorArgumentListTooLong = "argument_list_too_long"
+	startErrorBadWorkingDirectory = "bad_working_directory"
+	startErrorExecFormat          = "exec_format_error"
+	startErrorNotFound            = "not_found"
+	startErrorPermissionDenied    = "permission_denied"
+	startErrorPolicyDenied        = "policy_denied"
+	startErrorResourceUnavailable = "resource_unavailable"
+	startErrorSettingFailed       = "setting_failed"
+	startErrorTextFileBusy        = "text_file_busy"
+	startErrorUnknown             = "unknown"
+)
+
+// classifyStartError returns the type of error which prevented the command from starting.
+func classifyStartError(command *exec.Cmd, err error) string {
+	var settingErr *settingError
+	if errors.As(err, &settingErr) {
+		if settingErr.setting == settingWorkingDirectory {
+			return startErrorBadWorkingDirectory
+		}
+		return startErrorSettingFailed
+	}
+
+	if command.Dir != "" {
+		if info, statErr := os.Stat(command.Dir); statErr != nil || !info.IsDir() {
+			return startErrorBadWorkingDirectory
+		}
+	}
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) && pathErr.Op == "chdir" {
+		return startErrorBadWorkingDirectory
+	}
+
+	switch {
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, syscall.ENOENT):
+		return startErrorNotFound
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM), errors.Is(err, os.ErrPermission):
+		return startErrorPermissionDenied
+	case errors.Is(err, syscall.ENOEXEC):
+		return startErrorExecFormat
+	case errors.Is(err, syscall.ETXTBSY):
+		return startErrorTextFileBusy
+	case errors.Is(err, syscall.E2BIG):
+		return startErrorArgumentListTooLong
+	case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.ENOMEM):
+		return startErrorResourceUnavailable
+	}
+	return startErrorUnknown
+}
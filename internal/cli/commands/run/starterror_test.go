@@ -0,0 +1,48 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+// TestClassifyStartError checks that errors which prevent the command from starting are classified by their cause.
+func TestClassifyStartError(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+		err  error
+		want string
+	}{
+		{name: "not in path", err: &exec.Error{Name: "nope", Err: exec.ErrNotFound}, want: startErrorNotFound},
+		{name: "no such file", err: &os.PathError{Op: "fork/exec", Path: "/nope", Err: syscall.ENOENT},
+			want: startErrorNotFound},
+		{name: "permission denied", err: &os.PathError{Op: "fork/exec", Path: "/etc/passwd", Err: syscall.EACCES},
+			want: startErrorPermissionDenied},
+		{name: "exec format", err: &os.PathError{Op: "fork/exec", Path: "/bin/x", Err: syscall.ENOEXEC},
+			want: startErrorExecFormat},
+		{name: "text file busy", err: &os.PathError{Op: "fork/exec", Path: "/bin/x", Err: syscall.ETXTBSY},
+			want: startErrorTextFileBusy},
+		{name: "argument list too long", err: &os.PathError{Op: "fork/exec", Path: "/bin/x", Err: syscall.E2BIG},
+			want: startErrorArgumentListTooLong},
+		{name: "resource unavailable", err: &os.PathError{Op: "fork/exec", Path: "/bin/x", Err: syscall.EAGAIN},
+			want: startErrorResourceUnavailable},
+		{name: "chdir", err: &os.PathError{Op: "chdir", Path: "/nope", Err: syscall.ENOENT},
+			want: startErrorBadWorkingDirectory},
+		{name: "missing directory", dir: "/nonexistent/json-exec", err: &os.PathError{Op: "fork/exec",
+			Path: "/bin/x", Err: syscall.ENOENT}, want: startErrorBadWorkingDirectory},
+		{name: "setting", err: &settingError{setting: "user", err: fmt.Errorf("unknown user 'nope'")},
+			want: startErrorSettingFailed},
+		{name: "working directory setting", err: &settingError{setting: settingWorkingDirectory,
+			err: syscall.ENOENT}, want: startErrorBadWorkingDirectory},
+		{name: "unknown", err: fmt.Errorf("something else"), want: startErrorUnknown},
+	}
+	for _, tt := range tests {
+		command := &exec.Cmd{Dir: tt.dir}
+		if got := classifyStartError(command, tt.err); got != tt.want {
+			t.Errorf("%s: classifyStartError() = %s, expected %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package run
+
+import (
+	"os"
+	"syscall"
+)
+
+// exitStatus contains details about how the command terminated.
+type exitStatus struct {
+	// unexported members
+	coreDumped bool
+	oomKilled  bool
+	signal     syscall.Signal
+	signaled   bool
+}
+
+// getExitStatus retrieves details about how the process terminated from its state.
+//
+// reported is the signal which terminated the program as reported by the helper when it stayed behind as the init
+// process of the sandbox, if any, since the helper itself exits normally in that case.
+//
+// oomKillsBefore is the number of OOM kills in the application's cgroup before the command was started. If the
+// process was killed by SIGKILL and the number of OOM kills has increased since then, it is assumed that the process
+// was killed by the OOM killer.
+func getExitStatus(state *os.ProcessState, reported *helperStatus, oomKillsBefore int64,
+	oomKillsKnown bool) exitStatus {
+	var status exitStatus
+	if state == nil {
+		return status
+	}
+	if reported != nil {
+		status.signal = syscall.Signal(reported.Signal)
+		status.coreDumped = reported.CoreDumped
+	} else {
+		ws, ok := state.Sys().(syscall.WaitStatus)
+		if !ok || !ws.Signaled() {
+			return status
+		}
+		status.signal = ws.Signal()
+		status.coreDumped = ws.CoreDump()
+	}
+
+	status.signaled = true
+	if oomKillsKnown && status.signal == syscall.SIGKILL {
+		if oomKillsAfter, ok := readOOMKillCount(); ok && oomKillsAfter > oomKillsBefore {
+			status.oomKilled = true
+		}
+	}
+	return status
+}
+
+// String returns a description of how the process terminated in the same format as the process state.
+func (s exitStatus) String() string {
+	if !s.signaled {
+		return ""
+	}
+	if s.coreDumped {
+		return "signal: " + s.signal.String() + " (core dumped)"
+	}
+	return "signal: " + s.signal.String()
+}
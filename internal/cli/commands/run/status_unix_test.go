@@ -0,0 +1,63 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+// TestGetExitStatus checks that the termination signal is retrieved from the process state or the helper.
+func TestGetExitStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		reported *helperStatus
+		expected string
+	}{
+		{
+			name:     "exited normally",
+			script:   "exit 3",
+			expected: "",
+		},
+		{
+			name:     "signaled",
+			script:   "kill -TERM $$",
+			expected: "signal: " + syscall.SIGTERM.String(),
+		},
+		{
+			name:     "reported by helper",
+			script:   "exit 0",
+			reported: &helperStatus{Signal: int(syscall.SIGINT)},
+			expected: "signal: " + syscall.SIGINT.String(),
+		},
+		{
+			name:     "reported core dump",
+			script:   "exit 0",
+			reported: &helperStatus{Signal: int(syscall.SIGSEGV), CoreDumped: true},
+			expected: "signal: " + syscall.SIGSEGV.String() + " (core dumped)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			cmd.Run()
+			status := getExitStatus(cmd.ProcessState, tt.reported, 0, false)
+			if s := status.String(); s != tt.expected {
+				t.Errorf("getExitStatus(%q) = %q, expected %q", tt.script, s, tt.expected)
+			}
+			if status.signaled != (tt.expected != "") {
+				t.Errorf("signaled = %v, expected %v", status.signaled, tt.expected != "")
+			}
+			if status.oomKilled {
+				t.Errorf("oomKilled = true, expected false")
+			}
+		})
+	}
+
+	if status := getExitStatus(nil, &helperStatus{Signal: int(syscall.SIGKILL)}, 0, true); status.signaled {
+		t.Errorf("getExitStatus(nil) = %q, expected %q", status.String(), "")
+	}
+}
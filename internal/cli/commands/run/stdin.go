@@ -0,0 +1,214 @@
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// stdinRecorder records the number of bytes, SHA-256 checksum and, optionally, the content of stdin.
+type stdinRecorder struct {
+	// unexported members
+	complete bool
+	content  *boundedBuffer
+	hash     hash.Hash
+	mode     string
+	mu       sync.Mutex
+	total    int64
+}
+
+// stdinSource provides stdin to the command.
+//
+// Sources which are files are given to the command directly. Any other source is copied to the command through a
+// pipe so that waiting for the command to finish does not also wait for the source to be exhausted.
+type stdinSource struct {
+	// unexported members
+	file     *os.File
+	pipe     *os.File
+	reader   io.Reader
+	recorder *stdinRecorder
+	started  bool
+	writer   *os.File
+}
+
+// newStdinSource creates a new stdinSource object using the given stdin options.
+//
+// If the source is a literal string or a file, it is recorded before the command starts. Forwarded stdin is recorded
+// as it is passed to the command. At most limit bytes of the content are recorded; a limit of 0 records all
+// of the content.
+func newStdinSource(cfg *config.StdinConfig, limit int) (*stdinSource, error) {
+	s := &stdinSource{}
+	if cfg.Record {
+		s.recorder = &stdinRecorder{
+			hash: sha256.New(),
+			mode: cfg.Mode,
+		}
+		if cfg.RecordContent {
+			s.recorder.content = newBoundedBuffer(limit)
+		}
+	}
+
+	switch cfg.Mode {
+	case config.StdinData:
+		s.reader = strings.NewReader(cfg.Data)
+		if s.recorder != nil {
+			s.recorder.Write([]byte(cfg.Data))
+			s.recorder.complete = true
+		}
+	case config.StdinFile:
+		file, err := os.Open(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stdin file: %s", err.Error())
+		}
+		s.file = file
+		s.reader = file
+		if s.recorder != nil {
+			if err := s.recordFile(file); err != nil {
+				return nil, fmt.Errorf("failed to read stdin file: %s", err.Error())
+			}
+		}
+	case config.StdinForward:
+		s.reader = os.Stdin
+		if s.recorder != nil {
+			s.reader = io.TeeReader(os.Stdin, s.recorder)
+		}
+	}
+	return s, nil
+}
+
+// recordFile records the entire content of the stdin file before the command starts.
+//
+// Regular files are rewound after recording them. Any other file, such as a named pipe, can only be read once, so its
+// content is copied to an unlinked temporary file which is given to the command instead. The file is closed if an
+// error occurs.
+func (s *stdinSource) recordFile(file *os.File) error {
+	s.recorder.complete = true
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+		if _, err := io.Copy(s.recorder, file); err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return err
+		}
+		return nil
+	}
+
+	defer file.Close()
+	temp, err := os.CreateTemp("", "json-exec-stdin-*")
+	if err != nil {
+		return err
+	}
+	os.Remove(temp.Name())
+	if _, err := io.Copy(io.MultiWriter(temp, s.recorder), file); err != nil {
+		temp.Close()
+		return err
+	}
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		temp.Close()
+		return err
+	}
+	s.file = temp
+	s.reader = temp
+	return nil
+}
+
+// Attach configures the command to read from the source.
+func (s *stdinSource) Attach(command *exec.Cmd) error {
+	switch reader := s.reader.(type) {
+	case nil:
+		command.Stdin = nil
+	case *os.File:
+		command.Stdin = reader
+	default:
+		r, w, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("failed to create stdin pipe: %s", err.Error())
+		}
+		s.pipe, s.writer = r, w
+		command.Stdin = r
+	}
+	return nil
+}
+
+// Start closes the end of the pipe used by the command, which must have been started, and copies the source to it.
+func (s *stdinSource) Start() {
+	if s.pipe == nil {
+		return
+	}
+	s.pipe.Close()
+	s.started = true
+	go func() {
+		defer s.writer.Close()
+		io.Copy(s.writer, s.reader) // copying stops early if the command closes stdin
+	}()
+}
+
+// Reader returns the source of stdin, which is nil if stdin is closed.
+func (s *stdinSource) Reader() io.Reader {
+	return s.reader
+}
+
+// Recorder returns the recorder for stdin, which is nil if stdin is not recorded.
+func (s *stdinSource) Recorder() *stdinRecorder {
+	return s.recorder
+}
+
+// Close closes any files opened for the source.
+//
+// The end of the pipe used to copy the source is left to the copying goroutine unless copying never started.
+func (s *stdinSource) Close() {
+	if s.file != nil {
+		s.file.Close()
+	}
+	if s.pipe != nil && !s.started {
+		s.pipe.Close()
+		s.writer.Close()
+	}
+}
+
+// Write records the data passed to stdin.
+func (r *stdinRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.total += int64(len(p))
+	r.hash.Write(p)
+	if r.content != nil {
+		r.content.Write(p)
+	}
+	return len(p), nil
+}
+
+// Complete returns whether or not all of stdin was recorded before the command started.
+func (r *stdinRecorder) Complete() bool {
+	return r.complete
+}
+
+// AddFields adds the recorded details of stdin to the logger context.
+func (r *stdinRecorder) AddFields(ctx zerolog.Context) zerolog.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx = ctx.
+		Str("stdin_mode", r.mode).
+		Int64("stdin_bytes", r.total).
+		Str("stdin_sha256", hex.EncodeToString(r.hash.Sum(nil)))
+	if r.content != nil {
+		ctx = ctx.Str("stdin_content", r.content.String())
+		if r.content.Truncated() {
+			ctx = ctx.Bool("stdin_truncated", true)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,74 @@
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// TestNewStdinSource checks that stdin is read from the configured source and recorded before the command starts.
+func TestNewStdinSource(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stdin.txt")
+	if err := os.WriteFile(file, []byte("from a file\n"), 0644); err != nil {
+		t.Fatalf("failed to create stdin file: %s", err.Error())
+	}
+	tests := []struct {
+		name    string
+		cfg     config.StdinConfig
+		want    string
+		content string
+		err     bool
+	}{
+		{name: "close", cfg: config.StdinConfig{Mode: config.StdinClose}},
+		{name: "data", cfg: config.StdinConfig{Mode: config.StdinData, Data: "hello"}, want: "hello"},
+		{name: "recorded data", cfg: config.StdinConfig{Mode: config.StdinData, Data: "hello world", Record: true,
+			RecordContent: true}, want: "hello world", content: "hell\n[... 3 bytes truncated ...]\norld"},
+		{name: "recorded file", cfg: config.StdinConfig{Mode: config.StdinFile, File: file, Record: true},
+			want: "from a file\n"},
+		{name: "missing file", cfg: config.StdinConfig{Mode: config.StdinFile, File: file + ".missing"}, err: true},
+	}
+	for _, tt := range tests {
+		s, err := newStdinSource(&tt.cfg, 8)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: newStdinSource() error = %v, expected error = %t", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if s.Reader() == nil {
+			if tt.want != "" {
+				t.Errorf("%s: Reader() = nil, expected %q", tt.name, tt.want)
+			}
+			continue
+		}
+
+		// the recorded file must have been rewound so that the command can read it
+		data, err := io.ReadAll(s.Reader())
+		s.Close()
+		if err != nil || string(data) != tt.want {
+			t.Errorf("%s: read %q, %v, expected %q", tt.name, data, err, tt.want)
+		}
+		r := s.Recorder()
+		if r == nil {
+			if tt.cfg.Record {
+				t.Errorf("%s: Recorder() = nil", tt.name)
+			}
+			continue
+		}
+		sum := sha256.Sum256([]byte(tt.want))
+		if !r.Complete() || r.total != int64(len(tt.want)) || hex.EncodeToString(r.hash.Sum(nil)) !=
+			hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: recorded %d bytes (complete = %t), expected %d", tt.name, r.total, r.Complete(),
+				len(tt.want))
+		}
+		if r.content != nil && r.content.String() != tt.content {
+			t.Errorf("%s: recorded content %q, expected %q", tt.name, r.content.String(), tt.content)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package run
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+)
+
+// lineWriter is an io.Writer that passes each line of output written to it to a lineProcessor.
+//
+// Output that is not terminated by a newline is held until the next newline arrives or Flush() is called. At most
+// limit bytes of each line are kept; a limit of 0 keeps entire lines. If multiline grouping is enabled, lines are
+// passed to the processor through a lineGrouper.
+type lineWriter struct {
+	// unexported members
+	grouper   *lineGrouper
+	line      *boundedBuffer
+	lines     int
+	mu        sync.Mutex
+	processor *lineProcessor
+	stream    string
+}
+
+// newLineWriter creates a new lineWriter object for the given stream name.
+func newLineWriter(stream string, limit int, processor *lineProcessor, multiline *config.MultilineConfig) *lineWriter {
+	w := &lineWriter{
+		line:      newBoundedBuffer(limit),
+		processor: processor,
+		stream:    stream,
+	}
+	if multiline.Enabled() {
+		w.grouper = newLineGrouper(multiline, processor)
+	}
+	return w
+}
+
+// Write splits the data into lines and processes every complete line.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line.Write(p)
+			break
+		}
+		w.line.Write(p[:i])
+		w.emit()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush processes any remaining output that was not terminated by a newline along with any grouped lines.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.line.Total() > 0 {
+		w.emit()
+	}
+	if w.grouper != nil {
+		w.grouper.Flush()
+	}
+}
+
+// emit passes the current line of output to the processor.
+func (w *lineWriter) emit() {
+	w.lines++
+	line := outputLine{
+		number:     w.lines,
+		stream:     w.stream,
+		text:       strings.TrimSuffix(w.line.String(), "\r"),
+		totalBytes: w.line.Total(),
+		truncated:  w.line.Truncated(),
+	}
+	w.line.Reset()
+	if w.grouper != nil {
+		w.grouper.Add(line)
+	} else {
+		w.processor.Process(line)
+	}
+}
@@ -0,0 +1,100 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+
+	"go.sophtrust.dev/json-exec/internal/config"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// TestLineWriter checks that output written in arbitrary chunks is split into numbered lines.
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		writes    []string
+		lines     []string
+		truncated []int64
+	}{
+		{
+			name:   "single line",
+			writes: []string{"hello\n"},
+			lines:  []string{"hello"},
+		},
+		{
+			name:   "multiple lines in one write",
+			writes: []string{"a\nb\nc\n"},
+			lines:  []string{"a", "b", "c"},
+		},
+		{
+			name:   "line split across writes",
+			writes: []string{"he", "llo\nwor", "ld\n"},
+			lines:  []string{"hello", "world"},
+		},
+		{
+			name:   "partial line flushed",
+			writes: []string{"a\nb"},
+			lines:  []string{"a", "b"},
+		},
+		{
+			name:   "empty lines",
+			writes: []string{"\n\na\n"},
+			lines:  []string{"", "", "a"},
+		},
+		{
+			name:   "carriage return trimmed",
+			writes: []string{"a\r\nb\r", "\n"},
+			lines:  []string{"a", "b"},
+		},
+		{
+			name:      "line limit",
+			limit:     3,
+			writes:    []string{"abcdef\nab\n", "abcd", "ef\n"},
+			lines:     []string{"a\n[... 3 bytes truncated ...]\nef", "ab", "a\n[... 3 bytes truncated ...]\nef"},
+			truncated: []int64{6, 0, 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.AppConfig
+			if err := yaml.Unmarshal([]byte("{}"), &cfg.Run); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			recorder := recordMessages(t)
+			w := newLineWriter(config.StreamStdout, tt.limit, newLineProcessor(&cfg, cfg.Run.Parse), &cfg.Run.Multiline)
+			for _, data := range tt.writes {
+				if n, err := w.Write([]byte(data)); n != len(data) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v, expected %d, nil", data, n, err, len(data))
+				}
+			}
+			w.Flush()
+
+			lines := []string{}
+			for i, message := range recorder.Messages(t) {
+				text, _ := message[zerolog.MessageFieldName].(string) // empty messages are omitted
+				lines = append(lines, text)
+				if message["stream"] != config.StreamStdout {
+					t.Errorf("stream = %v, expected %q", message["stream"], config.StreamStdout)
+				}
+				if message["line"] != float64(i+1) {
+					t.Errorf("line = %v, expected %d", message["line"], i+1)
+				}
+				var total int64
+				if tt.truncated != nil {
+					total = tt.truncated[i]
+				}
+				if total == 0 && message["truncated"] != nil {
+					t.Errorf("line %d: unexpected truncated field", i+1)
+				} else if total > 0 && (message["truncated"] != true || message["total_bytes"] != float64(total)) {
+					t.Errorf("line %d: truncated = %v, total_bytes = %v, expected true, %d", i+1,
+						message["truncated"], message["total_bytes"], total)
+				}
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("lines = %q, expected %q", lines, tt.lines)
+			}
+		})
+	}
+}
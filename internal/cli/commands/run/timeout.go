@@ -0,0 +1,87 @@
+package run
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/signals"
+	"go.sophtrust.dev/pkg/zerolog/v2/log"
+)
+
+// timeoutWatcher signals a running command once its timeout expires.
+type timeoutWatcher struct {
+	// unexported members
+	done     chan struct{}
+	mu       sync.Mutex
+	signal   syscall.Signal
+	timedOut bool
+	wg       sync.WaitGroup
+}
+
+// startTimeoutWatcher starts watching the process and sends the signal to its process group once the timeout
+// expires.
+//
+// If the process is still running after the killAfter grace period, SIGKILL is sent to the process group. A
+// killAfter value of 0 disables the escalation.
+func startTimeoutWatcher(process *os.Process, timeout, killAfter time.Duration,
+	sig syscall.Signal) *timeoutWatcher {
+
+	w := &timeoutWatcher{
+		done: make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		select {
+		case <-w.done:
+			return
+		case <-time.After(timeout):
+		}
+		w.send(process, sig)
+		log.Warn().
+			Str("signal", signals.Name(sig)).
+			Msgf("command timed out after %s: sent %s", timeout, signals.Name(sig))
+
+		if killAfter <= 0 {
+			return
+		}
+		select {
+		case <-w.done:
+			return
+		case <-time.After(killAfter):
+		}
+		w.send(process, syscall.SIGKILL)
+		log.Warn().
+			Str("signal", signals.Name(syscall.SIGKILL)).
+			Msgf("command still running %s after timeout: sent %s", killAfter, signals.Name(syscall.SIGKILL))
+	}()
+	return w
+}
+
+// Stop stops watching the process and returns whether or not the timeout expired along with the last signal
+// that was sent to the process.
+func (w *timeoutWatcher) Stop() (bool, syscall.Signal) {
+	close(w.done)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.timedOut, w.signal
+}
+
+// send sends the signal to the process group and records that the timeout expired.
+func (w *timeoutWatcher) send(process *os.Process, sig syscall.Signal) {
+	w.mu.Lock()
+	w.timedOut = true
+	w.signal = sig
+	w.mu.Unlock()
+
+	if err := signalProcessGroup(process, sig); err != nil {
+		log.Error().
+			Err(err).
+			Str("signal", signals.Name(sig)).
+			Msgf("failed to send %s to command: %s", signals.Name(sig), err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// umaskSupported indicates whether or not the file mode creation mask of the command can be set.
+const umaskSupported = true
+
+// umaskLock serializes changes to the process-wide file mode creation mask.
+var umaskLock sync.Mutex
+
+// readUmask returns the file mode creation mask of json-exec.
+func readUmask() int {
+	umaskLock.Lock()
+	defer umaskLock.Unlock()
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return mask
+}
+
+// startWithUmask starts the command with the given file mode creation mask.
+//
+// The mask is shared by every thread of json-exec so it is only changed for as long as it takes to fork the command.
+// A negative mask starts the command with the mask of json-exec.
+func startWithUmask(command *exec.Cmd, mask int) error {
+	if mask < 0 {
+		return command.Start()
+	}
+	umaskLock.Lock()
+	defer umaskLock.Unlock()
+	previous := syscall.Umask(mask)
+	defer syscall.Umask(previous)
+	return command.Start()
+}
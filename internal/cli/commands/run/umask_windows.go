@@ -0,0 +1,16 @@
+package run
+
+import "os/exec"
+
+// umaskSupported indicates whether or not the file mode creation mask of the command can be set.
+const umaskSupported = false
+
+// readUmask always returns -1 since Windows does not have a file mode creation mask.
+func readUmask() int {
+	return -1
+}
+
+// startWithUmask starts the command, ignoring the mask since Windows does not have one.
+func startWithUmask(command *exec.Cmd, mask int) error {
+	return command.Start()
+}
@@ -5,6 +5,9 @@ type AppConfig struct {
 	// Global holds the global configuration settings.
 	Global GlobalConfig `yaml:"global"`
 
+	// Policy holds the rules which determine whether or not a command may be executed.
+	Policy PolicyConfig `yaml:"policy"`
+
 	// Run holds the "run" command configuration settings.
 	Run RunConfig `yaml:"run"`
 
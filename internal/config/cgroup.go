@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCgroupCPUPeriod is the period in microseconds used when the CPU limit is given as a number of CPUs.
+const DefaultCgroupCPUPeriod = 100000
+
+// CgroupConfig contains the options for running the command in its own cgroup v2 subtree.
+type CgroupConfig struct {
+	// CPUMax is the CPU bandwidth limit of the cgroup in the format of the cpu.max file ("$MAX $PERIOD").
+	//
+	// If empty, the limit is not set.
+	CPUMax string `yaml:"-"`
+
+	// CPUMaxRaw represents the string version of the CPU bandwidth limit, either as a number of CPUs (eg: "1.5") or
+	// in the format of the cpu.max file (eg: "50000 100000").
+	CPUMaxRaw string `yaml:"cpu_max"`
+
+	// Enabled indicates whether or not to run the command in its own cgroup.
+	//
+	// It is automatically enabled if any limit is set.
+	Enabled bool `yaml:"enabled"`
+
+	// MemoryMax is the memory limit of the cgroup in bytes.
+	//
+	// If negative, the limit is not set.
+	MemoryMax int64 `yaml:"-"`
+
+	// MemoryMaxRaw represents the string version of the memory limit (eg: "512M").
+	MemoryMaxRaw string `yaml:"memory_max"`
+
+	// Parent is the path of the cgroup, relative to the root of the cgroup v2 hierarchy, under which the cgroup of
+	// the command is created.
+	//
+	// If empty, the cgroup is created under the cgroup of json-exec.
+	Parent string `yaml:"parent"`
+
+	// PidsMax is the maximum number of processes in the cgroup.
+	//
+	// If 0, the limit is not set.
+	PidsMax int `yaml:"pids_max"`
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *CgroupConfig) validate() error {
+	var err error
+	if c.MemoryMax, err = parseByteSize(c.MemoryMaxRaw); err != nil {
+		return fmt.Errorf("invalid cgroup memory limit '%s': %s", c.MemoryMaxRaw, err.Error())
+	}
+	if c.CPUMax, err = parseCPUMax(c.CPUMaxRaw); err != nil {
+		return fmt.Errorf("invalid cgroup CPU limit '%s': %s", c.CPUMaxRaw, err.Error())
+	}
+	if c.PidsMax < 0 {
+		return fmt.Errorf("invalid cgroup process limit %d: value must not be negative", c.PidsMax)
+	}
+	if c.Parent != "" && !strings.HasPrefix(c.Parent, "/") {
+		c.Parent = "/" + c.Parent
+	}
+	if c.MemoryMax >= 0 || c.CPUMax != "" || c.PidsMax > 0 {
+		c.Enabled = true
+	}
+	return nil
+}
+
+// parseCPUMax converts a CPU bandwidth limit into the format of the cpu.max file.
+func parseCPUMax(s string) (string, error) {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 0:
+		return "", nil
+	case 1:
+		cpus, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || cpus <= 0 {
+			return "", fmt.Errorf("must be a positive number of CPUs or a quota and period in microseconds")
+		}
+		quota := int64(cpus * DefaultCgroupCPUPeriod)
+		if quota < 1000 {
+			return "", fmt.Errorf("value must be at least 0.01 CPUs")
+		}
+		return fmt.Sprintf("%d %d", quota, DefaultCgroupCPUPeriod), nil
+	case 2:
+		quota, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || quota < 1000 {
+			return "", fmt.Errorf("quota must be a number of microseconds of at least 1000")
+		}
+		period, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || period < 1000 || period > 1000000 {
+			return "", fmt.Errorf("period must be a number of microseconds between 1000 and 1000000")
+		}
+		return fmt.Sprintf("%d %d", quota, period), nil
+	}
+	return "", fmt.Errorf("must be a positive number of CPUs or a quota and period in microseconds")
+}
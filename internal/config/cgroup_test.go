@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseCPUMax checks that CPU limits given as a number of CPUs or a quota and period are converted.
+func TestParseCPUMax(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+		err  string
+	}{
+		{s: "", want: ""},
+		{s: "1", want: "100000 100000"},
+		{s: "1.5", want: "150000 100000"},
+		{s: " 0.01 ", want: "1000 100000"},
+		{s: "50000 100000", want: "50000 100000"},
+		{s: "1000  1000", want: "1000 1000"},
+		{s: "0", err: "must be a positive number of CPUs"},
+		{s: "-2", err: "must be a positive number of CPUs"},
+		{s: "half", err: "must be a positive number of CPUs"},
+		{s: "0.001", err: "value must be at least 0.01 CPUs"},
+		{s: "999 100000", err: "quota must be a number of microseconds of at least 1000"},
+		{s: "max 100000", err: "quota must be a number of microseconds"},
+		{s: "50000 999", err: "period must be a number of microseconds between 1000 and 1000000"},
+		{s: "50000 1000001", err: "period must be a number of microseconds"},
+		{s: "1 2 3", err: "must be a positive number of CPUs or a quota and period"},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUMax(tt.s)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("parseCPUMax(%q) failed: %s", tt.s, err.Error())
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("parseCPUMax(%q) = %v, expected error containing '%s'", tt.s, err, tt.err)
+		case tt.err == "" && got != tt.want:
+			t.Errorf("parseCPUMax(%q) = %q, expected %q", tt.s, got, tt.want)
+		}
+	}
+}
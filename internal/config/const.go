@@ -9,6 +9,9 @@ const (
 	// DefaultConfigName is the default configuration file name without an extension.
 	DefaultConfigName = "json-exec"
 
+	// DefaultIONiceLevel is the default I/O scheduling priority level of the command.
+	DefaultIONiceLevel = 4
+
 	// DefaultLogLevel is the default logging level.
 	DefaultLogLevel = zerolog.InfoLevel
 
@@ -21,6 +24,31 @@ const (
 	// DefaultLogTimestampFieldName is the name of the timestamp field in log messages.
 	DefaultLogTimestampFieldName = "@timestamp"
 
+	// DefaultParseConflictPrefix is the prefix added to parsed fields which conflict with reserved fields.
+	DefaultParseConflictPrefix = "child_"
+
+	// DefaultPtyCols is the default number of columns in the pseudo-terminal window.
+	DefaultPtyCols = 80
+
+	// DefaultPtyRows is the default number of rows in the pseudo-terminal window.
+	DefaultPtyRows = 24
+
+	// MaxCPUs is the maximum number of CPUs supported when setting the CPU affinity of the command.
+	MaxCPUs = 1024
+
 	// EnvPrefix is the prefix used for configuration via environment variables.
 	EnvPrefix = "JSON_EXEC"
 )
+
+var (
+	// DefaultLevelRules is the list of rules used to set the level of output lines when none are configured.
+	DefaultLevelRules = []LevelRule{
+		{LevelRaw: "error", Pattern: `\b(?:ERROR|FATAL)\b|panic:`},
+	}
+
+	// DefaultParseLevelFields is the list of parsed fields which commonly contain the level of an output line.
+	DefaultParseLevelFields = []string{"level", "lvl", "severity", "@level", "log.level"}
+
+	// DefaultParseMessageFields is the list of parsed fields which commonly contain the message of an output line.
+	DefaultParseMessageFields = []string{"msg", "message", "@message"}
+)
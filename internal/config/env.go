@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfig contains the options for building the environment of the command.
+type EnvConfig struct {
+	// Allow is the list of patterns matching the names of variables which are inherited from json-exec.
+	//
+	// If empty, every variable is inherited unless the environment is cleared.
+	Allow []string `yaml:"allow"`
+
+	// Clear indicates whether or not to start with an empty environment rather than inheriting from json-exec.
+	Clear bool `yaml:"clear"`
+
+	// Deny is the list of patterns matching the names of variables which are not inherited from json-exec.
+	Deny []string `yaml:"deny"`
+
+	// Files is the list of dotenv-formatted files containing variables to set, in the order they are loaded.
+	Files []string `yaml:"files"`
+
+	// Record indicates whether or not the variables in the environment are recorded.
+	Record bool `yaml:"record"`
+
+	// RecordValues is the list of patterns matching the names of variables whose values are recorded.
+	//
+	// The values of all other variables are redacted.
+	RecordValues []string `yaml:"record_values"`
+
+	// Vars is the list of variables to set in KEY=VALUE format, which are set after those in the files.
+	//
+	// A variable given as just KEY takes its value from the environment of json-exec, if it is set.
+	Vars []string `yaml:"vars"`
+}
+type _yamlEnvConfig EnvConfig // wrapper to avoid infinite recursion
+
+// UnmarshalYAML decodes the raw YAML into the object.
+//
+// The files and variables given on the command-line are passed through by viper as a single string in the same
+// bracketed, comma-separated format pflag uses to print them, so they are converted back into lists first. Unlike
+// list flags which split their values on commas, this allows variables whose values contain commas.
+func (c *EnvConfig) UnmarshalYAML(value *yaml.Node) error {
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		switch value.Content[i].Value {
+		case "files", "vars":
+			if err := decodeFlagArray(value.Content[i+1]); err != nil {
+				return fmt.Errorf("invalid environment %s '%s': %s", value.Content[i].Value,
+					value.Content[i+1].Value, err.Error())
+			}
+		}
+	}
+
+	// unmarshal into a temporary object so we don't overwrite existing settings if the operation fails
+	var cfg _yamlEnvConfig
+	if err := value.Decode(&cfg); err != nil {
+		return err
+	}
+	*c = EnvConfig(cfg)
+	return nil
+}
+
+// Customized returns whether or not the environment differs from that of json-exec.
+func (c *EnvConfig) Customized() bool {
+	return c.Clear || len(c.Allow) > 0 || len(c.Deny) > 0 || len(c.Files) > 0 || len(c.Vars) > 0
+}
+
+// Inherits returns whether or not the variable with the given name is inherited from json-exec.
+func (c *EnvConfig) Inherits(name string) bool {
+	if c.Clear {
+		return false
+	}
+	if len(c.Allow) > 0 && !matchesAny(c.Allow, name) {
+		return false
+	}
+	return !matchesAny(c.Deny, name)
+}
+
+// RecordsValue returns whether or not the value of the variable with the given name is recorded.
+func (c *EnvConfig) RecordsValue(name string) bool {
+	return matchesAny(c.RecordValues, name)
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *EnvConfig) validate() error {
+	for _, patterns := range [][]string{c.Allow, c.Deny, c.RecordValues} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid environment variable pattern '%s': %s", p, err.Error())
+			}
+		}
+	}
+	for i, file := range c.Files {
+		c.Files[i] = os.ExpandEnv(file)
+	}
+	for _, v := range c.Vars {
+		if strings.SplitN(v, "=", 2)[0] == "" {
+			return fmt.Errorf("invalid environment variable '%s': name must not be empty", v)
+		}
+	}
+	if len(c.RecordValues) > 0 {
+		c.Record = true
+	}
+	return nil
+}
+
+// decodeFlagArray converts a string holding the value of a string array flag into a sequence node.
+//
+// Nodes which are not such a string are left as they are.
+func decodeFlagArray(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" || !strings.HasPrefix(node.Value, "[") ||
+		!strings.HasSuffix(node.Value, "]") {
+		return nil
+	}
+	values, err := csv.NewReader(strings.NewReader(node.Value[1 : len(node.Value)-1])).Read()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	content := make([]*yaml.Node, 0, len(values))
+	for _, v := range values {
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+	}
+	*node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: content}
+	return nil
+}
+
+// matchesAny returns whether or not the name matches any of the patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestEnvConfigUnmarshalYAML checks that files and variables given as lists or in the string format used for flags
+// are decoded.
+func TestEnvConfigUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		err   string
+		files []string
+		vars  []string
+	}{
+		{name: "empty", yaml: "{}"},
+		{name: "lists", yaml: "files: [a.env, b.env]\nvars: ['A=1,2', B]", files: []string{"a.env", "b.env"},
+			vars: []string{"A=1,2", "B"}},
+		{name: "flag strings", yaml: `{files: "[a.env,b.env]", vars: "[\"A=1,2\",B=]"}`,
+			files: []string{"a.env", "b.env"}, vars: []string{"A=1,2", "B="}},
+		{name: "empty flag string", yaml: `vars: "[]"`, vars: []string{}},
+		{name: "invalid flag string", yaml: `vars: "[\"A]"`, err: "invalid environment vars"},
+		{name: "invalid type", yaml: "clear: maybe", err: "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg EnvConfig
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err.Error())
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error containing '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("expected error containing '%s', got '%s'", tt.err, err.Error())
+			case tt.err != "":
+				return
+			}
+			if !reflect.DeepEqual(cfg.Files, tt.files) || !reflect.DeepEqual(cfg.Vars, tt.vars) {
+				t.Errorf("Files = %q, Vars = %q, expected %q, %q", cfg.Files, cfg.Vars, tt.files, tt.vars)
+			}
+		})
+	}
+}
+
+// TestEnvConfigInherits checks that variables are inherited according to the allow and deny patterns.
+func TestEnvConfigInherits(t *testing.T) {
+	tests := []struct {
+		cfg  EnvConfig
+		name string
+		want bool
+	}{
+		{cfg: EnvConfig{}, name: "PATH", want: true},
+		{cfg: EnvConfig{Clear: true}, name: "PATH", want: false},
+		{cfg: EnvConfig{Allow: []string{"LC_*", "PATH"}}, name: "LC_ALL", want: true},
+		{cfg: EnvConfig{Allow: []string{"LC_*", "PATH"}}, name: "HOME", want: false},
+		{cfg: EnvConfig{Deny: []string{"*_TOKEN"}}, name: "GITHUB_TOKEN", want: false},
+		{cfg: EnvConfig{Allow: []string{"GITHUB_*"}, Deny: []string{"*_TOKEN"}}, name: "GITHUB_TOKEN", want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.Inherits(tt.name); got != tt.want {
+			t.Errorf("%+v: Inherits(%s) = %t, expected %t", tt.cfg, tt.name, got, tt.want)
+		}
+	}
+}
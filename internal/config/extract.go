@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/grok"
+)
+
+// ExtractRule contains the options for extracting fields from lines of output using a regular expression.
+type ExtractRule struct {
+	// Name is an optional name for the rule which is used in error messages.
+	Name string `yaml:"name"`
+
+	// Pattern is the regular expression used to extract fields from the output line.
+	//
+	// Fields are extracted from named capture groups. The pattern may also reference grok-style patterns using the
+	// syntax %{NAME}, %{NAME:field} or %{NAME:field:type}.
+	Pattern string `yaml:"pattern"`
+
+	// Regexp is the compiled version of the pattern.
+	Regexp *regexp.Regexp `yaml:"-"`
+
+	// Streams is the list of streams to which the rule applies.
+	//
+	// If empty, the rule applies to both stdout and stderr.
+	Streams []string `yaml:"streams"`
+
+	// Types maps the names of extracted fields to the type the values should be converted to.
+	//
+	// Field names are not case-sensitive. Valid types are bool, duration, float, int and string.
+	Types map[string]string `yaml:"types"`
+}
+
+// AppliesTo returns whether or not the rule applies to the given stream.
+func (r *ExtractRule) AppliesTo(stream string) bool {
+	return streamsInclude(r.Streams, stream)
+}
+
+// TypeOf returns the type the value of the given field should be converted to.
+func (r *ExtractRule) TypeOf(field string) string {
+	if t, ok := r.Types[strings.ToLower(field)]; ok {
+		return t
+	}
+	return grok.TypeString
+}
+
+// compile validates the rule and compiles its pattern using the given custom grok patterns.
+func (r *ExtractRule) compile(index int, patterns map[string]string) error {
+	name := r.Name
+	if name == "" {
+		name = fmt.Sprintf("#%d", index+1)
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("extraction rule %s must have a pattern", name)
+	}
+
+	p, err := grok.Compile(r.Pattern, patterns)
+	if err != nil {
+		return fmt.Errorf("failed to compile pattern for extraction rule %s: %s", name, err.Error())
+	}
+	hasFields := false
+	for _, field := range p.Regexp.SubexpNames() {
+		if field != "" {
+			hasFields = true
+			break
+		}
+	}
+	if !hasFields {
+		return fmt.Errorf("pattern for extraction rule %s does not capture any named fields", name)
+	}
+	r.Regexp = p.Regexp
+
+	if err := normalizeStreams(r.Streams); err != nil {
+		return fmt.Errorf("invalid extraction rule %s: %s", name, err.Error())
+	}
+
+	// types given in the pattern itself take precedence
+	types := make(map[string]string, len(r.Types)+len(p.Types))
+	for field, t := range r.Types {
+		types[strings.ToLower(field)] = strings.ToLower(t)
+	}
+	for field, t := range p.Types {
+		types[strings.ToLower(field)] = t
+	}
+	for field, t := range types {
+		if !grok.IsValidType(t) {
+			return fmt.Errorf("invalid type '%s' for field '%s' in extraction rule %s: must be one of: "+
+				"bool, duration, float, int or string", t, field, name)
+		}
+	}
+	r.Types = types
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LandlockConfig contains the options for restricting the files the command may access using Landlock.
+type LandlockConfig struct {
+	// ReadOnly is the list of paths beneath which the command may read and execute files.
+	ReadOnly []string `yaml:"read_only"`
+
+	// ReadWrite is the list of paths beneath which the command may read, write, execute, create and remove files.
+	ReadWrite []string `yaml:"read_write"`
+}
+
+// Enabled returns whether or not the files the command may access are restricted.
+func (c *LandlockConfig) Enabled() bool {
+	return len(c.ReadOnly) > 0 || len(c.ReadWrite) > 0
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *LandlockConfig) validate() error {
+	var err error
+	if c.ReadOnly, err = parseLandlockPaths(c.ReadOnly); err != nil {
+		return err
+	}
+	if c.ReadWrite, err = parseLandlockPaths(c.ReadWrite); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseLandlockPaths expands and cleans the given list of paths, skipping empty entries.
+func parseLandlockPaths(raw []string) ([]string, error) {
+	paths := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if p == "" {
+			continue
+		}
+		path := os.ExpandEnv(p)
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("invalid Landlock path '%s': path must be absolute", p)
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths, nil
+}
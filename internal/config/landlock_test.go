@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestLandlockConfigValidate checks that Landlock paths must be absolute and are cleaned.
+func TestLandlockConfigValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		err       string
+		readOnly  []string
+		readWrite []string
+	}{
+		{name: "empty", yaml: "{}", readOnly: []string{}, readWrite: []string{}},
+		{name: "paths", yaml: "{read_only: [/usr/, '', /etc/../opt], read_write: ['$JSON_EXEC_TEST_LANDLOCK/']}",
+			readOnly: []string{"/usr", "/opt"}, readWrite: []string{"/tmp/json-exec"}},
+		{name: "relative read-only path", yaml: "{read_only: [usr]}", err: "invalid Landlock path 'usr'"},
+		{name: "relative read-write path", yaml: "{read_write: [./tmp]}", err: "invalid Landlock path './tmp'"},
+	}
+	os.Setenv("JSON_EXEC_TEST_LANDLOCK", "/tmp/json-exec")
+	defer os.Unsetenv("JSON_EXEC_TEST_LANDLOCK")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg RunConfig
+			err := yaml.Unmarshal([]byte("landlock: "+tt.yaml), &cfg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err.Error())
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error containing '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("expected error containing '%s', got '%s'", tt.err, err.Error())
+			case tt.err != "":
+				return
+			}
+			if !reflect.DeepEqual(cfg.Landlock.ReadOnly, tt.readOnly) ||
+				!reflect.DeepEqual(cfg.Landlock.ReadWrite, tt.readWrite) {
+				t.Errorf("ReadOnly = %q, ReadWrite = %q, expected %q, %q", cfg.Landlock.ReadOnly,
+					cfg.Landlock.ReadWrite, tt.readOnly, tt.readWrite)
+			}
+			if cfg.Landlock.Enabled() != (len(tt.readOnly)+len(tt.readWrite) > 0) {
+				t.Errorf("Enabled() = %t", cfg.Landlock.Enabled())
+			}
+		})
+	}
+}
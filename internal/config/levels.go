@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.sophtrust.dev/json-exec/internal/grok"
+	"go.sophtrust.dev/pkg/zerolog/v2"
+)
+
+// LevelConfig contains the options for determining the level of each line of output from the command.
+type LevelConfig struct {
+	// Rules is the list of rules which set the level of output lines matching a pattern.
+	//
+	// The first matching rule is used. If nil, the default rules are used.
+	Rules []LevelRule `yaml:"rules"`
+
+	// Stderr is the level of lines from stderr which are not matched by any rule.
+	Stderr zerolog.Level `yaml:"-"`
+
+	// StderrRaw represents the string version of the stderr level.
+	StderrRaw string `yaml:"stderr"`
+
+	// Stdout is the level of lines from stdout which are not matched by any rule.
+	Stdout zerolog.Level `yaml:"-"`
+
+	// StdoutRaw represents the string version of the stdout level.
+	StdoutRaw string `yaml:"stdout"`
+}
+
+// LevelRule contains the options for setting the level of output lines which match a pattern.
+type LevelRule struct {
+	// Level is the level of matching lines.
+	Level zerolog.Level `yaml:"-"`
+
+	// LevelRaw represents the string version of the level.
+	LevelRaw string `yaml:"level"`
+
+	// Pattern is the regular expression matched against the output line.
+	//
+	// The pattern may reference grok-style patterns using the syntax %{NAME}.
+	Pattern string `yaml:"pattern"`
+
+	// Regexp is the compiled version of the pattern.
+	Regexp *regexp.Regexp `yaml:"-"`
+
+	// Streams is the list of streams to which the rule applies.
+	//
+	// If empty, the rule applies to both stdout and stderr.
+	Streams []string `yaml:"streams"`
+}
+
+// LevelOf returns the level of the output line from the given stream.
+func (c *LevelConfig) LevelOf(stream, text string) zerolog.Level {
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if streamsInclude(rule.Streams, stream) && rule.Regexp.MatchString(text) {
+			return rule.Level
+		}
+	}
+	if stream == StreamStderr {
+		return c.Stderr
+	}
+	return c.Stdout
+}
+
+// compile validates the options and compiles the rules using the given custom grok patterns.
+func (c *LevelConfig) compile(patterns map[string]string) error {
+	var err error
+	if c.Stdout, err = parseLevel(c.StdoutRaw, zerolog.InfoLevel); err != nil {
+		return fmt.Errorf("failed to parse stdout level '%s': %s", c.StdoutRaw, err.Error())
+	}
+	if c.Stderr, err = parseLevel(c.StderrRaw, zerolog.WarnLevel); err != nil {
+		return fmt.Errorf("failed to parse stderr level '%s': %s", c.StderrRaw, err.Error())
+	}
+
+	if c.Rules == nil {
+		c.Rules = append([]LevelRule(nil), DefaultLevelRules...)
+	}
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if rule.Pattern == "" {
+			return fmt.Errorf("level rule #%d must have a pattern", i+1)
+		}
+		p, err := grok.Compile(rule.Pattern, patterns)
+		if err != nil {
+			return fmt.Errorf("failed to compile pattern for level rule #%d: %s", i+1, err.Error())
+		}
+		rule.Regexp = p.Regexp
+		if rule.Level, err = parseLevel(rule.LevelRaw, zerolog.NoLevel); err != nil {
+			return fmt.Errorf("failed to parse level '%s' for level rule #%d: %s", rule.LevelRaw, i+1, err.Error())
+		}
+		if rule.Level == zerolog.NoLevel {
+			return fmt.Errorf("level rule #%d must have a level", i+1)
+		}
+		if err := normalizeStreams(rule.Streams); err != nil {
+			return fmt.Errorf("invalid level rule #%d: %s", i+1, err.Error())
+		}
+	}
+	return nil
+}
+
+// parseLevel parses the name of a logging level, returning the default level if the name is empty.
+func parseLevel(s string, defaultLevel zerolog.Level) (zerolog.Level, error) {
+	if s == "" {
+		return defaultLevel, nil
+	}
+	switch level, err := zerolog.ParseLevel(strings.ToLower(s)); {
+	case err != nil, level == zerolog.NoLevel, level == zerolog.Disabled:
+		return zerolog.NoLevel, fmt.Errorf("must be one of: trace, debug, info, warn, error, fatal or panic")
+	default:
+		return level, nil
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// TestParseLevel checks that level names are parsed and that empty names use the default level.
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s     string
+		want  zerolog.Level
+		valid bool
+	}{
+		{s: "", want: zerolog.WarnLevel, valid: true},
+		{s: "trace", want: zerolog.TraceLevel, valid: true},
+		{s: "DEBUG", want: zerolog.DebugLevel, valid: true},
+		{s: "Info", want: zerolog.InfoLevel, valid: true},
+		{s: "error", want: zerolog.ErrorLevel, valid: true},
+		{s: "panic", want: zerolog.PanicLevel, valid: true},
+		{s: "disabled"},
+		{s: "verbose"},
+	}
+	for _, tt := range tests {
+		got, err := parseLevel(tt.s, zerolog.WarnLevel)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseLevel(%q) error = %v, expected valid = %t", tt.s, err, tt.valid)
+			continue
+		}
+		if tt.valid && got != tt.want {
+			t.Errorf("parseLevel(%q) = %s, expected %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestLevelConfigLevelOf checks that the first matching rule for the stream determines the level of a line.
+func TestLevelConfigLevelOf(t *testing.T) {
+	var cfg RunConfig
+	data := `
+levels:
+  stdout: debug
+  rules:
+    - pattern: '^%{LOGLEVEL:level} fatal'
+      level: fatal
+    - pattern: '(?i)warn'
+      level: warn
+      streams: [stdout]
+    - pattern: 'error'
+      level: error
+`
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	tests := []struct {
+		stream string
+		text   string
+		want   zerolog.Level
+	}{
+		{stream: StreamStdout, text: "all good", want: zerolog.DebugLevel},
+		{stream: StreamStderr, text: "all good", want: zerolog.WarnLevel},
+		{stream: StreamStdout, text: "WARNING: low disk", want: zerolog.WarnLevel},
+		{stream: StreamStderr, text: "error: bad thing", want: zerolog.ErrorLevel},
+		{stream: StreamStdout, text: "warn then error", want: zerolog.WarnLevel},
+		{stream: StreamStderr, text: "warn then error", want: zerolog.ErrorLevel},
+		{stream: StreamStderr, text: "ERROR fatal error", want: zerolog.FatalLevel},
+	}
+	for _, tt := range tests {
+		if got := cfg.Levels.LevelOf(tt.stream, tt.text); got != tt.want {
+			t.Errorf("LevelOf(%s, %q) = %s, expected %s", tt.stream, tt.text, got, tt.want)
+		}
+	}
+}
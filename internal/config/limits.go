@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// _byteSizeUnits maps the suffixes of byte sizes to their multipliers.
+var _byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// LimitsConfig contains the POSIX resource limits of the command.
+type LimitsConfig struct {
+	// AddressSpace is the maximum size of the virtual memory of the command in bytes.
+	//
+	// If negative, the limit is not changed.
+	AddressSpace int64 `yaml:"-"`
+
+	// AddressSpaceRaw represents the string version of the address space limit (eg: "512M").
+	AddressSpaceRaw string `yaml:"address_space"`
+
+	// Core is the maximum size of a core file dumped by the command in bytes.
+	//
+	// If negative, the limit is not changed. A limit of 0 disables core dumps.
+	Core int64 `yaml:"-"`
+
+	// CoreRaw represents the string version of the core file size limit.
+	CoreRaw string `yaml:"core"`
+
+	// CPU is the maximum amount of CPU time the command may use.
+	//
+	// If 0, the limit is not changed.
+	CPU time.Duration `yaml:"-"`
+
+	// CPURaw represents the string version of the CPU time limit.
+	CPURaw string `yaml:"cpu"`
+
+	// FileSize is the maximum size of a file written by the command in bytes.
+	//
+	// If negative, the limit is not changed.
+	FileSize int64 `yaml:"-"`
+
+	// FileSizeRaw represents the string version of the file size limit.
+	FileSizeRaw string `yaml:"file_size"`
+
+	// OpenFiles is the maximum number of files the command may have open at once.
+	//
+	// If 0, the limit is not changed.
+	OpenFiles int `yaml:"open_files"`
+
+	// Processes is the maximum number of processes which may run as the user of the command.
+	//
+	// If 0, the limit is not changed.
+	Processes int `yaml:"processes"`
+}
+
+// Enabled returns whether or not any limit is set.
+func (c *LimitsConfig) Enabled() bool {
+	return c.AddressSpace >= 0 || c.Core >= 0 || c.CPU > 0 || c.FileSize >= 0 || c.OpenFiles > 0 || c.Processes > 0
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *LimitsConfig) validate() error {
+	var err error
+	if c.AddressSpace, err = parseByteSize(c.AddressSpaceRaw); err != nil {
+		return fmt.Errorf("invalid address space limit '%s': %s", c.AddressSpaceRaw, err.Error())
+	}
+	if c.Core, err = parseByteSize(c.CoreRaw); err != nil {
+		return fmt.Errorf("invalid core file size limit '%s': %s", c.CoreRaw, err.Error())
+	}
+	if c.FileSize, err = parseByteSize(c.FileSizeRaw); err != nil {
+		return fmt.Errorf("invalid file size limit '%s': %s", c.FileSizeRaw, err.Error())
+	}
+	if c.CPU, err = parseDuration(c.CPURaw); err != nil {
+		return fmt.Errorf("invalid CPU time limit '%s': %s", c.CPURaw, err.Error())
+	}
+	if c.CPU > 0 && c.CPU < time.Second {
+		return fmt.Errorf("invalid CPU time limit '%s': value must be at least 1s", c.CPURaw)
+	}
+	if c.OpenFiles < 0 {
+		return fmt.Errorf("invalid open files limit %d: value must not be negative", c.OpenFiles)
+	}
+	if c.Processes < 0 {
+		return fmt.Errorf("invalid processes limit %d: value must not be negative", c.Processes)
+	}
+	return nil
+}
+
+// parseByteSize parses a number of bytes with an optional unit suffix (eg: "512M" or "2GiB").
+//
+// Units are powers of 1024. An empty string returns -1.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return -1, nil
+	}
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	multiplier, ok := _byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if i == 0 || !ok {
+		return 0, fmt.Errorf("must be a number of bytes with an optional unit: K, M, G or T")
+	}
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil || n > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("value is too large")
+	}
+	return n * multiplier, nil
+}
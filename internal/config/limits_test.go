@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseByteSize checks that byte sizes with and without units are parsed.
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		err  string
+	}{
+		{s: "", want: -1},
+		{s: "0", want: 0},
+		{s: "512", want: 512},
+		{s: "100b", want: 100},
+		{s: "4k", want: 4 << 10},
+		{s: " 512M ", want: 512 << 20},
+		{s: "2GiB", want: 2 << 30},
+		{s: "1 TB", want: 1 << 40},
+		{s: "8388607T", want: 8388607 << 40},
+		{s: "8388608T", err: "value is too large"},
+		{s: "99999999999999999999", err: "value is too large"},
+		{s: "M", err: "must be a number of bytes"},
+		{s: "1.5G", err: "must be a number of bytes"},
+		{s: "-1", err: "must be a number of bytes"},
+		{s: "10PB", err: "must be a number of bytes"},
+	}
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.s)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("parseByteSize(%q) failed: %s", tt.s, err.Error())
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("parseByteSize(%q) = %v, expected error containing '%s'", tt.s, err, tt.err)
+		case tt.err == "" && got != tt.want:
+			t.Errorf("parseByteSize(%q) = %d, expected %d", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policies for handling keys which appear more than once in a logfmt output line.
+const (
+	// DuplicateKeysArray collects every value of the key into an array.
+	DuplicateKeysArray = "array"
+
+	// DuplicateKeysFirst keeps the first value of the key.
+	DuplicateKeysFirst = "first"
+
+	// DuplicateKeysLast keeps the last value of the key.
+	DuplicateKeysLast = "last"
+)
+
+// Quoting rules for values in logfmt output lines.
+const (
+	// QuotingAny allows values to be surrounded by either double or single quotes.
+	QuotingAny = "any"
+
+	// QuotingDouble allows values to be surrounded by double quotes.
+	QuotingDouble = "double"
+
+	// QuotingNone treats quotes as part of the value.
+	QuotingNone = "none"
+)
+
+// LogfmtConfig contains the options for parsing logfmt output lines.
+type LogfmtConfig struct {
+	// DuplicateKeys determines how keys which appear more than once in a line are handled.
+	DuplicateKeys string `yaml:"duplicate_keys"`
+
+	// Quoting determines which quotes may surround values.
+	Quoting string `yaml:"quoting"`
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *LogfmtConfig) validate() error {
+	c.DuplicateKeys = strings.ToLower(c.DuplicateKeys)
+	switch c.DuplicateKeys {
+	case "":
+		c.DuplicateKeys = DuplicateKeysLast
+	case DuplicateKeysArray, DuplicateKeysFirst, DuplicateKeysLast:
+	default:
+		return fmt.Errorf("invalid duplicate keys policy '%s': must be one of: array, first or last", c.DuplicateKeys)
+	}
+
+	c.Quoting = strings.ToLower(c.Quoting)
+	switch c.Quoting {
+	case "":
+		c.Quoting = QuotingDouble
+	case QuotingAny, QuotingDouble, QuotingNone:
+	default:
+		return fmt.Errorf("invalid quoting rule '%s': must be one of: any, double or none", c.Quoting)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/grok"
+)
+
+// Presets for grouping multiline output.
+const (
+	// MultilinePresetGo groups Go panics and fatal errors along with their goroutine traces.
+	MultilinePresetGo = "go"
+
+	// MultilinePresetJava groups Java exceptions with their stack traces and causes.
+	MultilinePresetJava = "java"
+
+	// MultilinePresetNode groups Node.js errors with their stack traces.
+	MultilinePresetNode = "node"
+
+	// MultilinePresetNone disables multiline grouping unless patterns are given.
+	MultilinePresetNone = "none"
+
+	// MultilinePresetPython groups Python tracebacks.
+	MultilinePresetPython = "python"
+)
+
+// _multilinePresets maps each preset to its start and continuation patterns.
+var _multilinePresets = map[string][2]string{
+	MultilinePresetGo: {
+		`^(?:panic: |fatal error: )`,
+		`^$|^goroutine \d+ \[|^\t|^[\w./*()\[\]-]+\(.*\)$|^\[signal |^created by |^exit status \d+$`,
+	},
+	MultilinePresetJava: {
+		``,
+		`^\s+at\s|^\s+\.\.\. \d+ (?:more|common frames omitted)|^\s*Caused by: |^\s+Suppressed: `,
+	},
+	MultilinePresetNode: {
+		``,
+		`^\s+at\s|^\s+\.\.\. \d+ lines matching|^\s+\[\w+\]: |^\s*\}$`,
+	},
+	MultilinePresetPython: {
+		`^Traceback \(most recent call last\):`,
+		`^[ \t]|^[\w.]+(?:Error|Exception|Exit|Interrupt|Warning|Iteration)\b`,
+	},
+}
+
+// MultilineConfig contains the options for grouping multiple lines of output into a single message.
+type MultilineConfig struct {
+	// Continuation is the regular expression matching lines which continue the current group.
+	Continuation string `yaml:"continuation"`
+
+	// ContinuationRegexp is the compiled version of the continuation pattern.
+	ContinuationRegexp *regexp.Regexp `yaml:"-"`
+
+	// MaxLines is the maximum number of lines in a group before it is emitted.
+	//
+	// A value of 0 does not limit the number of lines.
+	MaxLines int `yaml:"max_lines"`
+
+	// MaxWait is the maximum amount of time to wait for another line before the current group is emitted.
+	//
+	// A value of 0 waits until the next line which does not continue the group.
+	MaxWait time.Duration `yaml:"-"`
+
+	// MaxWaitRaw represents the string version of the maximum wait duration.
+	MaxWaitRaw string `yaml:"max_wait"`
+
+	// Preset is the name of a preset which provides the start and continuation patterns.
+	//
+	// Patterns which are given explicitly take precedence over those of the preset.
+	Preset string `yaml:"preset"`
+
+	// Start is the regular expression matching lines which start a new group.
+	Start string `yaml:"start"`
+
+	// StartRegexp is the compiled version of the start pattern.
+	StartRegexp *regexp.Regexp `yaml:"-"`
+}
+
+// Continues returns whether or not the line continues the current group.
+//
+// If only a start pattern is given, every line which does not match it continues the group. If only a continuation
+// pattern is given, every line which matches it continues the group. If both are given, only groups which began with
+// a line matching the start pattern may be continued by lines matching the continuation pattern.
+func (c *MultilineConfig) Continues(text string, started bool) bool {
+	switch {
+	case c.StartRegexp != nil && c.ContinuationRegexp != nil:
+		return started && c.ContinuationRegexp.MatchString(text)
+	case c.ContinuationRegexp != nil:
+		return c.ContinuationRegexp.MatchString(text)
+	case c.StartRegexp != nil:
+		return !c.StartRegexp.MatchString(text)
+	}
+	return false
+}
+
+// Enabled returns whether or not multiline grouping is enabled.
+func (c *MultilineConfig) Enabled() bool {
+	return c.StartRegexp != nil || c.ContinuationRegexp != nil
+}
+
+// Starts returns whether or not the line matches the start pattern.
+func (c *MultilineConfig) Starts(text string) bool {
+	return c.StartRegexp != nil && c.StartRegexp.MatchString(text)
+}
+
+// compile validates the options and compiles the patterns using the given custom grok patterns.
+func (c *MultilineConfig) compile(patterns map[string]string) error {
+	c.Preset = strings.ToLower(c.Preset)
+	switch c.Preset {
+	case "":
+		c.Preset = MultilinePresetNone
+	case MultilinePresetNone:
+	default:
+		preset, ok := _multilinePresets[c.Preset]
+		if !ok {
+			return fmt.Errorf("invalid multiline preset '%s': must be one of: none, go, java, node or python", c.Preset)
+		}
+		if c.Start == "" {
+			c.Start = preset[0]
+		}
+		if c.Continuation == "" {
+			c.Continuation = preset[1]
+		}
+	}
+
+	c.StartRegexp, c.ContinuationRegexp = nil, nil
+	if c.Start != "" {
+		p, err := grok.Compile(c.Start, patterns)
+		if err != nil {
+			return fmt.Errorf("failed to compile multiline start pattern: %s", err.Error())
+		}
+		c.StartRegexp = p.Regexp
+	}
+	if c.Continuation != "" {
+		p, err := grok.Compile(c.Continuation, patterns)
+		if err != nil {
+			return fmt.Errorf("failed to compile multiline continuation pattern: %s", err.Error())
+		}
+		c.ContinuationRegexp = p.Regexp
+	}
+
+	if c.MaxLines < 0 {
+		return fmt.Errorf("invalid multiline maximum lines %d: value must not be negative", c.MaxLines)
+	}
+	maxWait, err := parseDuration(c.MaxWaitRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse multiline maximum wait '%s': %s", c.MaxWaitRaw, err.Error())
+	}
+	c.MaxWait = maxWait
+	return nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats supported for parsing output lines.
+const (
+	// ParseFormatNone disables parsing of output lines.
+	ParseFormatNone = "none"
+
+	// ParseFormatJSON parses output lines which contain JSON objects.
+	ParseFormatJSON = "json"
+
+	// ParseFormatLogfmt parses output lines which contain logfmt-style key=value pairs.
+	ParseFormatLogfmt = "logfmt"
+)
+
+// Policies for handling fields parsed from output lines which conflict with fields already in the message.
+const (
+	// ConflictPolicyDrop discards the conflicting field parsed from the output line.
+	ConflictPolicyDrop = "drop"
+
+	// ConflictPolicyRename adds the conflicting field parsed from the output line with a prefix.
+	ConflictPolicyRename = "rename"
+)
+
+// ParseConfig contains the options for parsing lines of output from the command into fields.
+type ParseConfig struct {
+	// Commands contains parse options which override these options for specific commands.
+	Commands []CommandParseConfig `yaml:"-"`
+
+	// CommandsRaw contains the raw YAML of the command-specific parse options.
+	CommandsRaw []yaml.Node `yaml:"commands"`
+
+	// ConflictPolicy determines how parsed fields which conflict with reserved fields are handled.
+	ConflictPolicy string `yaml:"conflict_policy"`
+
+	// ConflictPrefix is the prefix added to conflicting fields when the conflict policy is "rename".
+	ConflictPrefix string `yaml:"conflict_prefix"`
+
+	// Format is the format of the output lines.
+	Format string `yaml:"format"`
+
+	// KeyPrefix is a prefix added to the name of every parsed field.
+	KeyPrefix string `yaml:"key_prefix"`
+
+	// LevelFields is the list of parsed fields which may contain the level of the output line.
+	LevelFields []string `yaml:"level_fields"`
+
+	// Logfmt contains the options for parsing logfmt output lines.
+	Logfmt LogfmtConfig `yaml:"logfmt"`
+
+	// MessageFields is the list of parsed fields which may contain the message of the output line.
+	MessageFields []string `yaml:"message_fields"`
+
+	// Namespace is the name of the field under which parsed fields are nested.
+	//
+	// If empty, the parsed fields are merged into the message itself.
+	Namespace string `yaml:"namespace"`
+
+	// Passthrough indicates whether or not the level and message of the output line are taken from parsed fields.
+	Passthrough bool `yaml:"passthrough"`
+}
+type _yamlParseConfig ParseConfig // wrapper to avoid infinite recursion
+
+// CommandParseConfig contains the parse options for specific commands.
+type CommandParseConfig struct {
+	// Match is the list of command names or paths to which the options apply.
+	Match []string
+
+	// Parse contains the parse options for the matching commands.
+	Parse ParseConfig
+}
+
+// UnmarshalYAML decodes the raw YAML into the object.
+//
+// It converts any raw values to their corresponding actual values and then performs validation on the
+// object member values. It may set default values as well, if necessary.
+//
+// Command-specific options are decoded on top of a copy of these options so that any settings which are not
+// specified for the command are inherited.
+func (c *ParseConfig) UnmarshalYAML(value *yaml.Node) error {
+	// unmarshal into a temporary object so we don't overwrite existing settings if the operation fails
+	var cfg _yamlParseConfig
+	if err := value.Decode(&cfg); err != nil {
+		return err
+	}
+	*c = ParseConfig(cfg)
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	c.Commands = make([]CommandParseConfig, 0, len(c.CommandsRaw))
+	for i, node := range c.CommandsRaw {
+		var match struct {
+			Match []string `yaml:"match"`
+		}
+		if err := node.Decode(&match); err != nil {
+			return err
+		}
+		if len(match.Match) == 0 {
+			return fmt.Errorf("command parse options #%d must match at least one command", i+1)
+		}
+
+		override := _yamlParseConfig(*c)
+		override.Commands = nil
+		override.CommandsRaw = nil
+		if err := node.Decode(&override); err != nil {
+			return err
+		}
+		parse := ParseConfig(override)
+		if err := parse.validate(); err != nil {
+			return fmt.Errorf("invalid parse options for command '%s': %s", match.Match[0], err.Error())
+		}
+		c.Commands = append(c.Commands, CommandParseConfig{
+			Match: match.Match,
+			Parse: parse,
+		})
+	}
+	return nil
+}
+
+// ForCommand returns the parse options for the command.
+//
+// The command may be identified by any number of names, such as the name it was invoked with and its absolute path.
+// The options of the first command-specific entry which matches one of the names or its base name, ignoring case,
+// are returned. If none match, these options are returned.
+func (c *ParseConfig) ForCommand(names ...string) ParseConfig {
+	for _, cmd := range c.Commands {
+		for _, m := range cmd.Match {
+			for _, name := range names {
+				if strings.EqualFold(m, name) || strings.EqualFold(m, filepath.Base(name)) {
+					return cmd.Parse
+				}
+			}
+		}
+	}
+	return *c
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *ParseConfig) validate() error {
+	c.Format = strings.ToLower(c.Format)
+	switch c.Format {
+	case "":
+		c.Format = ParseFormatNone
+	case ParseFormatNone, ParseFormatJSON, ParseFormatLogfmt:
+	default:
+		return fmt.Errorf("invalid parse format '%s': must be one of: none, json or logfmt", c.Format)
+	}
+
+	c.ConflictPolicy = strings.ToLower(c.ConflictPolicy)
+	switch c.ConflictPolicy {
+	case "":
+		c.ConflictPolicy = ConflictPolicyRename
+	case ConflictPolicyDrop, ConflictPolicyRename:
+	default:
+		return fmt.Errorf("invalid conflict policy '%s': must be one of: drop or rename", c.ConflictPolicy)
+	}
+	if c.ConflictPrefix == "" {
+		c.ConflictPrefix = DefaultParseConflictPrefix
+	}
+	if c.LevelFields == nil {
+		c.LevelFields = DefaultParseLevelFields
+	}
+	if c.MessageFields == nil {
+		c.MessageFields = DefaultParseMessageFields
+	}
+	return c.Logfmt.validate()
+}
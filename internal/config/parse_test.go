@@ -0,0 +1,138 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestParseConfigUnmarshalYAML checks that valid parse settings are accepted and invalid ones are rejected.
+func TestParseConfigUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		err   string
+		check func(t *testing.T, cfg *ParseConfig)
+	}{
+		{
+			name: "defaults",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *ParseConfig) {
+				if cfg.Format != ParseFormatNone || cfg.ConflictPolicy != ConflictPolicyRename ||
+					cfg.ConflictPrefix != DefaultParseConflictPrefix {
+					t.Errorf("unexpected parse settings: %s, %s, %s", cfg.Format, cfg.ConflictPolicy,
+						cfg.ConflictPrefix)
+				}
+				if len(cfg.LevelFields) != len(DefaultParseLevelFields) ||
+					len(cfg.MessageFields) != len(DefaultParseMessageFields) {
+					t.Errorf("unexpected default fields: %v, %v", cfg.LevelFields, cfg.MessageFields)
+				}
+			},
+		},
+		{
+			name: "json",
+			yaml: "format: JSON\nconflict_policy: Drop\nlevel_fields: [sev]\nmessage_fields: []",
+			check: func(t *testing.T, cfg *ParseConfig) {
+				if cfg.Format != ParseFormatJSON || cfg.ConflictPolicy != ConflictPolicyDrop {
+					t.Errorf("unexpected parse settings: %s, %s", cfg.Format, cfg.ConflictPolicy)
+				}
+				if len(cfg.LevelFields) != 1 || len(cfg.MessageFields) != 0 {
+					t.Errorf("unexpected fields: %v, %v", cfg.LevelFields, cfg.MessageFields)
+				}
+			},
+		},
+		{name: "invalid format", yaml: "format: xml", err: "invalid parse format 'xml'"},
+		{name: "invalid conflict policy", yaml: "conflict_policy: keep", err: "invalid conflict policy 'keep'"},
+
+		// command-specific options
+		{
+			name: "commands",
+			yaml: "format: json\nkey_prefix: app_\ncommands:\n  - match: [terraform]\n    format: logfmt",
+			check: func(t *testing.T, cfg *ParseConfig) {
+				if len(cfg.Commands) != 1 {
+					t.Fatalf("unexpected command parse options: %v", cfg.Commands)
+				}
+				parse := cfg.Commands[0].Parse
+				if parse.Format != ParseFormatLogfmt || parse.KeyPrefix != "app_" || parse.Commands != nil {
+					t.Errorf("unexpected command parse settings: %s, %s, %v", parse.Format, parse.KeyPrefix,
+						parse.Commands)
+				}
+			},
+		},
+		{name: "command without match", yaml: "commands:\n  - format: json", err: "must match at least one command"},
+		{
+			name: "invalid command format",
+			yaml: "commands:\n  - match: [terraform]\n    format: xml",
+			err:  "invalid parse options for command 'terraform'",
+		},
+
+		// logfmt options
+		{
+			name: "logfmt defaults",
+			yaml: "format: logfmt",
+			check: func(t *testing.T, cfg *ParseConfig) {
+				if cfg.Logfmt.DuplicateKeys != DuplicateKeysLast || cfg.Logfmt.Quoting != QuotingDouble {
+					t.Errorf("unexpected logfmt settings: %s, %s", cfg.Logfmt.DuplicateKeys, cfg.Logfmt.Quoting)
+				}
+			},
+		},
+		{
+			name: "logfmt",
+			yaml: "format: logfmt\nlogfmt:\n  duplicate_keys: Array\n  quoting: ANY",
+			check: func(t *testing.T, cfg *ParseConfig) {
+				if cfg.Logfmt.DuplicateKeys != DuplicateKeysArray || cfg.Logfmt.Quoting != QuotingAny {
+					t.Errorf("unexpected logfmt settings: %s, %s", cfg.Logfmt.DuplicateKeys, cfg.Logfmt.Quoting)
+				}
+			},
+		},
+		{
+			name: "invalid duplicate keys policy",
+			yaml: "logfmt:\n  duplicate_keys: merge",
+			err:  "invalid duplicate keys policy 'merge'",
+		},
+		{name: "invalid quoting rule", yaml: "logfmt:\n  quoting: backtick", err: "invalid quoting rule 'backtick'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg ParseConfig
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err.Error())
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error containing '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("expected error containing '%s', got '%s'", tt.err, err.Error())
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, &cfg)
+			}
+		})
+	}
+}
+
+// TestParseConfigForCommand checks that command-specific options are selected by name, path or base name.
+func TestParseConfigForCommand(t *testing.T) {
+	var cfg ParseConfig
+	data := "format: json\ncommands:\n  - match: [Terraform, /opt/bin/tool]\n    format: logfmt"
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{names: []string{"terraform"}, want: ParseFormatLogfmt},
+		{names: []string{"tf", "/usr/local/bin/terraform"}, want: ParseFormatLogfmt},
+		{names: []string{"/opt/bin/tool"}, want: ParseFormatLogfmt},
+		{names: []string{"/usr/bin/tool"}, want: ParseFormatJSON},
+		{names: []string{"terraform-docs"}, want: ParseFormatJSON},
+		{names: nil, want: ParseFormatJSON},
+	}
+	for _, tt := range tests {
+		if got := cfg.ForCommand(tt.names...).Format; got != tt.want {
+			t.Errorf("ForCommand(%q).Format = %s, expected %s", tt.names, got, tt.want)
+		}
+	}
+}
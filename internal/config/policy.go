@@ -0,0 +1,160 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions taken by policy rules.
+const (
+	// PolicyAllow allows the command to be executed.
+	PolicyAllow = "allow"
+
+	// PolicyDeny keeps the command from being executed.
+	PolicyDeny = "deny"
+)
+
+// PolicyConfig contains the rules which determine whether or not a command may be executed.
+type PolicyConfig struct {
+	// Default is the action taken when no rule matches the command.
+	//
+	// If empty, commands are denied when any rule allows commands and allowed otherwise.
+	Default string `yaml:"default"`
+
+	// Rules is the list of rules, the first of which matching the command determines whether or not it is executed.
+	Rules []PolicyRule `yaml:"rules"`
+}
+type _yamlPolicyConfig PolicyConfig // wrapper to avoid infinite recursion
+
+// PolicyRule matches commands by their binary and arguments.
+//
+// A rule matches a command when all of its criteria match.
+type PolicyRule struct {
+	// Action is the action taken when the rule matches the command.
+	Action string `yaml:"action"`
+
+	// Args is a regular expression which must match the arguments of the command joined with spaces.
+	Args string `yaml:"args"`
+
+	// ArgsRegexp is the compiled version of the argument pattern.
+	ArgsRegexp *regexp.Regexp `yaml:"-"`
+
+	// Glob is a pattern which must match the absolute path of the binary after resolving symbolic links.
+	Glob string `yaml:"glob"`
+
+	// Name is an optional name for the rule which is used in messages.
+	Name string `yaml:"name"`
+
+	// Path is the absolute path of the binary.
+	//
+	// Symbolic links are resolved in both the path and the path of the binary before they are compared.
+	Path string `yaml:"path"`
+
+	// SHA256 is the hex-encoded SHA-256 checksum of the binary.
+	SHA256 string `yaml:"sha256"`
+}
+
+// UnmarshalYAML decodes the raw YAML into the object.
+//
+// It converts any raw values to their corresponding actual values and then performs validation on the
+// object member values. It may set default values as well, if necessary.
+func (c *PolicyConfig) UnmarshalYAML(value *yaml.Node) error {
+	// unmarshal into a temporary object so we don't overwrite existing settings if the operation fails
+	var cfg _yamlPolicyConfig
+	if err := value.Decode(&cfg); err != nil {
+		return err
+	}
+	*c = PolicyConfig(cfg)
+
+	// validate rules
+	allows := false
+	for i := range c.Rules {
+		if err := c.Rules[i].compile(i); err != nil {
+			return err
+		}
+		allows = allows || c.Rules[i].Action == PolicyAllow
+	}
+
+	// deny anything which is not explicitly allowed when there is an allowlist
+	c.Default = strings.ToLower(c.Default)
+	switch c.Default {
+	case "":
+		c.Default = PolicyAllow
+		if allows {
+			c.Default = PolicyDeny
+		}
+	case PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("invalid default policy action '%s': must be one of: allow or deny", c.Default)
+	}
+	return nil
+}
+
+// Enabled returns whether or not any command may be denied.
+func (c *PolicyConfig) Enabled() bool {
+	return len(c.Rules) > 0 || c.Default == PolicyDeny
+}
+
+// NeedsSHA256 returns whether or not any rule matches commands by the checksum of their binary.
+func (c *PolicyConfig) NeedsSHA256() bool {
+	for _, rule := range c.Rules {
+		if rule.SHA256 != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// compile validates the rule and compiles its argument pattern.
+func (r *PolicyRule) compile(index int) error {
+	if r.Name == "" {
+		r.Name = fmt.Sprintf("#%d", index+1)
+	}
+	r.Action = strings.ToLower(r.Action)
+	switch r.Action {
+	case PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("invalid action '%s' for policy rule %s: must be one of: allow or deny", r.Action, r.Name)
+	}
+	if r.Args == "" && r.Glob == "" && r.Path == "" && r.SHA256 == "" {
+		return fmt.Errorf("policy rule %s must have a path, glob, sha256 or args", r.Name)
+	}
+
+	if r.Path != "" {
+		r.Path = os.ExpandEnv(r.Path)
+		if !filepath.IsAbs(r.Path) {
+			return fmt.Errorf("invalid path '%s' for policy rule %s: path must be absolute", r.Path, r.Name)
+		}
+		r.Path = filepath.Clean(r.Path)
+		if resolved, err := filepath.EvalSymlinks(r.Path); err == nil {
+			r.Path = resolved
+		}
+	}
+	if r.Glob != "" {
+		r.Glob = os.ExpandEnv(r.Glob)
+		if _, err := filepath.Match(r.Glob, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s' for policy rule %s: %s", r.Glob, r.Name, err.Error())
+		}
+	}
+	if r.SHA256 != "" {
+		r.SHA256 = strings.ToLower(r.SHA256)
+		if b, err := hex.DecodeString(r.SHA256); err != nil || len(b) != 32 {
+			return fmt.Errorf("invalid sha256 '%s' for policy rule %s: must be 64 hexadecimal digits", r.SHA256,
+				r.Name)
+		}
+	}
+	if r.Args != "" {
+		re, err := regexp.Compile(r.Args)
+		if err != nil {
+			return fmt.Errorf("failed to compile args pattern for policy rule %s: %s", r.Name, err.Error())
+		}
+		r.ArgsRegexp = re
+	}
+	return nil
+}
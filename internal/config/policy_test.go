@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestPolicyConfigUnmarshalYAML checks that valid policy rules are accepted and invalid ones are rejected.
+func TestPolicyConfigUnmarshalYAML(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("JSON_EXEC_TEST_POLICY", dir)
+	defer os.Unsetenv("JSON_EXEC_TEST_POLICY")
+	sum := strings.Repeat("aB", 32)
+
+	tests := []struct {
+		name  string
+		yaml  string
+		err   string
+		check func(t *testing.T, cfg *PolicyConfig)
+	}{
+		{
+			name: "empty",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *PolicyConfig) {
+				if cfg.Enabled() || cfg.Default != PolicyAllow || cfg.NeedsSHA256() {
+					t.Errorf("unexpected policy settings: %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "denylist",
+			yaml: "rules: [{action: DENY, args: '^-rf'}]",
+			check: func(t *testing.T, cfg *PolicyConfig) {
+				rule := cfg.Rules[0]
+				if !cfg.Enabled() || cfg.Default != PolicyAllow || rule.Name != "#1" || rule.Action != PolicyDeny ||
+					rule.ArgsRegexp == nil {
+					t.Errorf("unexpected policy settings: %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "allowlist",
+			yaml: "rules:\n  - {name: tool, action: allow, path: '$JSON_EXEC_TEST_POLICY/bin/../bin/tool'}\n" +
+				"  - {action: allow, glob: '$JSON_EXEC_TEST_POLICY/sbin/*', sha256: " + sum + "}",
+			check: func(t *testing.T, cfg *PolicyConfig) {
+				if cfg.Default != PolicyDeny || !cfg.NeedsSHA256() {
+					t.Errorf("unexpected policy settings: %+v", cfg)
+				}
+				if cfg.Rules[0].Path != filepath.Join(dir, "bin", "tool") {
+					t.Errorf("unexpected path: %s", cfg.Rules[0].Path)
+				}
+				if cfg.Rules[1].Glob != dir+"/sbin/*" || cfg.Rules[1].SHA256 != strings.ToLower(sum) {
+					t.Errorf("unexpected rule: %+v", cfg.Rules[1])
+				}
+			},
+		},
+		{
+			name: "explicit default",
+			yaml: "default: Allow\nrules: [{action: allow, args: x}]",
+			check: func(t *testing.T, cfg *PolicyConfig) {
+				if cfg.Default != PolicyAllow {
+					t.Errorf("unexpected default policy action: %s", cfg.Default)
+				}
+			},
+		},
+		{name: "invalid default", yaml: "default: ask", err: "invalid default policy action 'ask'"},
+		{name: "invalid action", yaml: "rules: [{name: x, action: log, args: y}]", err: "invalid action 'log'"},
+		{name: "no criteria", yaml: "rules: [{action: deny}]", err: "policy rule #1 must have a path, glob"},
+		{name: "relative path", yaml: "rules: [{action: deny, path: bin/rm}]", err: "invalid path 'bin/rm'"},
+		{name: "invalid glob", yaml: "rules: [{action: deny, glob: '/bin/['}]", err: "invalid glob '/bin/['"},
+		{name: "invalid sha256", yaml: "rules: [{action: deny, sha256: abc}]", err: "invalid sha256 'abc'"},
+		{name: "invalid args", yaml: "rules: [{action: deny, args: '('}]", err: "failed to compile args pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg PolicyConfig
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err.Error())
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error containing '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("expected error containing '%s', got '%s'", tt.err, err.Error())
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, &cfg)
+			}
+		})
+	}
+}
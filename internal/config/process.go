@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// I/O scheduling classes for the command.
+const (
+	// IONiceBestEffort is the default I/O scheduling class, with a priority level from 0 (highest) to 7 (lowest).
+	IONiceBestEffort = "best-effort"
+
+	// IONiceIdle only performs I/O when no other process needs the disk.
+	IONiceIdle = "idle"
+
+	// IONiceRealtime is given first access to the disk, with a priority level from 0 (highest) to 7 (lowest).
+	IONiceRealtime = "realtime"
+)
+
+// ProcessConfig contains the options for the execution context of the command.
+type ProcessConfig struct {
+	// CPUAffinity is the list of CPUs on which the command may run.
+	//
+	// If empty, the command may run on the same CPUs as json-exec.
+	CPUAffinity []int `yaml:"-"`
+
+	// CPUAffinityRaw represents the string version of the CPU list (eg: "0-3,6").
+	CPUAffinityRaw string `yaml:"cpu_affinity"`
+
+	// Cwd is the working directory of the command.
+	//
+	// If empty, the command runs in the working directory of json-exec.
+	Cwd string `yaml:"cwd"`
+
+	// Group is the name or ID of the primary group of the command.
+	//
+	// If empty, the command runs with the primary group of the user or, if no user is set, that of json-exec.
+	Group string `yaml:"group"`
+
+	// Groups is the list of names or IDs of the supplementary groups of the command.
+	//
+	// If empty, the command runs with the supplementary groups of the user or, if no user is set, those of json-exec.
+	Groups []string `yaml:"groups"`
+
+	// IONiceClass is the I/O scheduling class of the command.
+	//
+	// If empty, the command has the same I/O scheduling class as json-exec.
+	IONiceClass string `yaml:"ionice_class"`
+
+	// IONiceLevel is the I/O scheduling priority level of the command within the realtime and best-effort classes.
+	IONiceLevel int `yaml:"ionice_level"`
+
+	// Nice is the adjustment added to the niceness of json-exec to get the niceness of the command.
+	Nice int `yaml:"nice"`
+
+	// Umask is the file mode creation mask of the command.
+	//
+	// If negative, the command has the same mask as json-exec.
+	Umask int `yaml:"-"`
+
+	// UmaskRaw represents the octal string version of the file mode creation mask.
+	UmaskRaw string `yaml:"umask"`
+
+	// User is the name or ID of the user the command runs as.
+	//
+	// If empty, the command runs as the same user as json-exec.
+	User string `yaml:"user"`
+}
+
+// validate validates the options, setting default values where necessary.
+//
+// The working directory, user and groups are only checked when the command is run since they depend on the system
+// the command runs on.
+func (c *ProcessConfig) validate() error {
+	if c.Cwd != "" {
+		cwd, err := filepath.Abs(os.ExpandEnv(c.Cwd))
+		if err != nil {
+			return fmt.Errorf("failed to determine absolute path of working directory '%s': %s", c.Cwd, err.Error())
+		}
+		c.Cwd = cwd
+	}
+
+	c.Umask = -1
+	if c.UmaskRaw != "" {
+		umask, err := strconv.ParseUint(c.UmaskRaw, 8, 32)
+		if err != nil || umask > 0777 {
+			return fmt.Errorf("invalid umask '%s': must be an octal number between 0000 and 0777", c.UmaskRaw)
+		}
+		c.Umask = int(umask)
+	}
+
+	if c.Nice < -39 || c.Nice > 39 {
+		return fmt.Errorf("invalid nice adjustment %d: value must be between -39 and 39", c.Nice)
+	}
+
+	c.IONiceClass = strings.ToLower(c.IONiceClass)
+	switch c.IONiceClass {
+	case "", IONiceBestEffort, IONiceIdle, IONiceRealtime:
+	default:
+		return fmt.Errorf("invalid I/O scheduling class '%s': must be one of: realtime, best-effort or idle",
+			c.IONiceClass)
+	}
+	if c.IONiceLevel < 0 || c.IONiceLevel > 7 {
+		return fmt.Errorf("invalid I/O scheduling level %d: value must be between 0 and 7", c.IONiceLevel)
+	}
+
+	cpus, err := parseCPUList(c.CPUAffinityRaw)
+	if err != nil {
+		return fmt.Errorf("invalid CPU affinity '%s': %s", c.CPUAffinityRaw, err.Error())
+	}
+	c.CPUAffinity = cpus
+	return nil
+}
+
+// parseCPUList parses a comma-separated list of CPU numbers and ranges of CPU numbers (eg: "0-3,6").
+//
+// The CPU numbers are returned in ascending order without duplicates.
+func parseCPUList(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	seen := map[int]bool{}
+	cpus := []int{}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("'%s' is not a CPU number or range", part)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("'%s' is not a CPU number or range", part)
+			}
+		}
+		if last >= MaxCPUs {
+			return nil, fmt.Errorf("CPU numbers must be less than %d", MaxCPUs)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
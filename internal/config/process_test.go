@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseCPUList checks that lists of CPU numbers and ranges are parsed into sorted lists without duplicates.
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []int
+		err  string
+	}{
+		{s: "", want: nil},
+		{s: "  ", want: nil},
+		{s: "3", want: []int{3}},
+		{s: "0-3,6", want: []int{0, 1, 2, 3, 6}},
+		{s: " 6 , 2-3, 3-4 ", want: []int{2, 3, 4, 6}},
+		{s: "5-5", want: []int{5}},
+		{s: "1023", want: []int{1023}},
+		{s: "1024", err: "CPU numbers must be less than 1024"},
+		{s: "0-1024", err: "CPU numbers must be less than 1024"},
+		{s: "3-1", err: "'3-1' is not a CPU number or range"},
+		{s: "-1", err: "'-1' is not a CPU number or range"},
+		{s: "a", err: "'a' is not a CPU number or range"},
+		{s: "1,,2", err: "'' is not a CPU number or range"},
+		{s: "1-", err: "'1-' is not a CPU number or range"},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUList(tt.s)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("parseCPUList(%q) failed: %s", tt.s, err.Error())
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("parseCPUList(%q) = %v, expected error containing '%s'", tt.s, err, tt.err)
+		case tt.err == "" && !reflect.DeepEqual(got, tt.want):
+			t.Errorf("parseCPUList(%q) = %v, expected %v", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "fmt"
+
+// PtyConfig contains the options for running the command in a pseudo-terminal.
+type PtyConfig struct {
+	// Cols is the number of columns in the terminal window.
+	Cols int `yaml:"cols"`
+
+	// Enabled indicates whether or not the command is run in a pseudo-terminal.
+	Enabled bool `yaml:"enabled"`
+
+	// Rows is the number of rows in the terminal window.
+	Rows int `yaml:"rows"`
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *PtyConfig) validate() error {
+	if c.Cols < 0 || c.Cols > 65535 {
+		return fmt.Errorf("invalid terminal columns %d: value must be between 0 and 65535", c.Cols)
+	}
+	if c.Cols == 0 {
+		c.Cols = DefaultPtyCols
+	}
+	if c.Rows < 0 || c.Rows > 65535 {
+		return fmt.Errorf("invalid terminal rows %d: value must be between 0 and 65535", c.Rows)
+	}
+	if c.Rows == 0 {
+		c.Rows = DefaultPtyRows
+	}
+	return nil
+}
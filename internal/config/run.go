@@ -1,10 +1,295 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.sophtrust.dev/json-exec/internal/signals"
+	"gopkg.in/yaml.v3"
+)
+
+// Modes for handling ANSI escape sequences and carriage returns in the output of the command.
+const (
+	// ANSIAuto collapses the output when running in a pseudo-terminal and keeps it raw otherwise.
+	ANSIAuto = "auto"
+
+	// ANSICollapse removes escape sequences and keeps only the text after the last carriage return in each line.
+	ANSICollapse = "collapse"
+
+	// ANSIRaw keeps the output as it was written.
+	ANSIRaw = "raw"
+
+	// ANSIStrip removes escape sequences.
+	ANSIStrip = "strip"
+)
+
 // RunConfig contains the options for the "run" command.
 type RunConfig struct {
+	// ANSI determines how ANSI escape sequences and carriage returns in the output are handled.
+	ANSI string `yaml:"ansi"`
+
+	// Cgroup contains the options for running the command in its own cgroup v2 subtree.
+	Cgroup CgroupConfig `yaml:"cgroup"`
+
+	// Combined indicates whether or not to also capture the output of both streams in the order it was received.
+	//
+	// Since each stream is read from its own pipe, the order across the streams is best-effort: output written to
+	// both streams in quick succession may be received in a different order than it was written.
+	Combined bool `yaml:"combined"`
+
+	// Env contains the options for building the environment of the command.
+	Env EnvConfig `yaml:"env"`
+
+	// Extract is the list of rules for extracting fields from lines of output using regular expressions.
+	Extract []ExtractRule `yaml:"extract"`
+
+	// ForwardSignals is the list of signals which are forwarded to the command when they are received.
+	ForwardSignals []syscall.Signal `yaml:"-"`
+
+	// ForwardSignalsRaw represents the string version of the signals to forward.
+	ForwardSignalsRaw []string `yaml:"forward_signals"`
+
+	// ForwardToGroup indicates whether or not forwarded signals are sent to the entire process group of the command.
+	ForwardToGroup bool `yaml:"forward_to_group"`
+
+	// Init indicates whether or not to reap orphaned processes as an init process (or subreaper) would.
+	Init bool `yaml:"init"`
+
 	// IgnoreStderr indicates whether or not to ignore output from stderr.
 	IgnoreStderr bool `yaml:"ignore_stderr"`
 
 	// IgnoreStdout indicates whether or not to ignore output from stdout.
 	IgnoreStdout bool `yaml:"ignore_stdout"`
+
+	// Levels contains the options for determining the level of each line of output when streaming.
+	Levels LevelConfig `yaml:"levels"`
+
+	// KillAfter is the amount of time to wait after the timeout signal is sent before killing the command.
+	KillAfter time.Duration `yaml:"-"`
+
+	// KillAfterRaw represents the string version of the kill after duration.
+	KillAfterRaw string `yaml:"kill_after"`
+
+	// Landlock contains the options for restricting the files the command may access using Landlock.
+	Landlock LandlockConfig `yaml:"landlock"`
+
+	// Limits contains the POSIX resource limits of the command.
+	Limits LimitsConfig `yaml:"limits"`
+
+	// MaxOutputBytes is the maximum number of bytes of output kept from each stream or, when streaming, each line.
+	MaxOutputBytes int `yaml:"max_output_bytes"`
+
+	// Multiline contains the options for grouping multiple lines of output into a single message when streaming.
+	Multiline MultilineConfig `yaml:"multiline"`
+
+	// Parse contains the options for parsing lines of output from the command into fields.
+	Parse ParseConfig `yaml:"parse"`
+
+	// Patterns contains custom grok-style patterns which may be referenced by extraction rules.
+	Patterns map[string]string `yaml:"patterns"`
+
+	// Process contains the options for the execution context of the command.
+	Process ProcessConfig `yaml:"process"`
+
+	// Pty contains the options for running the command in a pseudo-terminal.
+	Pty PtyConfig `yaml:"pty"`
+
+	// Resources indicates whether or not to include timing and resource usage of the command in the result.
+	Resources bool `yaml:"resources"`
+
+	// SampleInterval is the interval at which the resource usage of the command is sampled.
+	SampleInterval time.Duration `yaml:"-"`
+
+	// SampleIntervalRaw represents the string version of the sample interval.
+	SampleIntervalRaw string `yaml:"sample_interval"`
+
+	// Sandbox contains the options for isolating the command using namespaces.
+	Sandbox SandboxConfig `yaml:"sandbox"`
+
+	// Seccomp contains the options for filtering the system calls the command may make using seccomp.
+	Seccomp SeccompConfig `yaml:"seccomp"`
+
+	// SpillDir is the directory in which files containing the full output are created.
+	SpillDir string `yaml:"spill_dir"`
+
+	// SpillOutput indicates whether or not to write the full output of each stream to a file when it is truncated.
+	SpillOutput bool `yaml:"spill_output"`
+
+	// Stdin contains the options for providing stdin to the command.
+	Stdin StdinConfig `yaml:"stdin"`
+
+	// Stream indicates whether or not to emit a message for each line of output as it is produced.
+	Stream bool `yaml:"stream"`
+
+	// Timeout is the maximum amount of time the command is allowed to run before it is signalled.
+	Timeout time.Duration `yaml:"-"`
+
+	// TimeoutRaw represents the string version of the timeout duration.
+	TimeoutRaw string `yaml:"timeout"`
+
+	// TimeoutSignal is the signal sent to the command when the timeout expires.
+	TimeoutSignal syscall.Signal `yaml:"-"`
+
+	// TimeoutSignalRaw represents the string version of the timeout signal.
+	TimeoutSignalRaw string `yaml:"timeout_signal"`
+}
+type _yamlRunConfig RunConfig // wrapper to avoid infinite recursion
+
+// UnmarshalYAML decodes the raw YAML into the object.
+//
+// It converts any raw values to their corresponding actual values and then performs validation on the
+// object member values. It may set default values as well, if necessary.
+func (c *RunConfig) UnmarshalYAML(value *yaml.Node) error {
+	// unmarshal into a temporary object so we don't overwrite existing settings if the operation fails
+	var cfg _yamlRunConfig
+	if err := value.Decode(&cfg); err != nil {
+		return err
+	}
+	*c = RunConfig(cfg)
+
+	// parse signal forwarding settings
+	c.ForwardSignals = make([]syscall.Signal, 0, len(c.ForwardSignalsRaw))
+	for _, raw := range c.ForwardSignalsRaw {
+		if raw == "" {
+			continue
+		}
+		sig, err := signals.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse forwarded signal '%s': %s", raw, err.Error())
+		}
+		if !signals.IsCatchable(sig) {
+			return fmt.Errorf("failed to parse forwarded signal '%s': signal cannot be caught", raw)
+		}
+		c.ForwardSignals = append(c.ForwardSignals, sig)
+	}
+
+	// compile extraction rules
+	for i := range c.Extract {
+		if err := c.Extract[i].compile(i, c.Patterns); err != nil {
+			return err
+		}
+	}
+
+	// compile level rules
+	if err := c.Levels.compile(c.Patterns); err != nil {
+		return err
+	}
+
+	// compile multiline settings
+	if err := c.Multiline.compile(c.Patterns); err != nil {
+		return err
+	}
+
+	// validate execution context settings
+	if err := c.Process.validate(); err != nil {
+		return err
+	}
+
+	// validate resource limit settings
+	if err := c.Limits.validate(); err != nil {
+		return err
+	}
+
+	// validate cgroup settings
+	if err := c.Cgroup.validate(); err != nil {
+		return err
+	}
+
+	// validate sandbox settings
+	if err := c.Sandbox.validate(); err != nil {
+		return err
+	}
+
+	// validate Landlock settings
+	if err := c.Landlock.validate(); err != nil {
+		return err
+	}
+
+	// validate seccomp settings
+	if err := c.Seccomp.validate(); err != nil {
+		return err
+	}
+
+	// validate environment settings
+	if err := c.Env.validate(); err != nil {
+		return err
+	}
+
+	// validate stdin settings
+	if err := c.Stdin.validate(); err != nil {
+		return err
+	}
+
+	// validate terminal settings
+	if err := c.Pty.validate(); err != nil {
+		return err
+	}
+	c.ANSI = strings.ToLower(c.ANSI)
+	switch c.ANSI {
+	case "", ANSIAuto:
+		c.ANSI = ANSIRaw
+		if c.Pty.Enabled {
+			c.ANSI = ANSICollapse
+		}
+	case ANSICollapse, ANSIRaw, ANSIStrip:
+	default:
+		return fmt.Errorf("invalid ANSI mode '%s': must be one of: auto, collapse, raw or strip", c.ANSI)
+	}
+
+	// validate output settings
+	if c.MaxOutputBytes < 0 {
+		return fmt.Errorf("invalid maximum output bytes %d: value must not be negative", c.MaxOutputBytes)
+	}
+	if c.SpillDir != "" {
+		c.SpillDir = os.ExpandEnv(c.SpillDir)
+	}
+
+	// parse sampling settings
+	sampleInterval, err := parseDuration(c.SampleIntervalRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse sample interval '%s': %s", c.SampleIntervalRaw, err.Error())
+	}
+	c.SampleInterval = sampleInterval
+
+	// parse timeout settings
+	timeout, err := parseDuration(c.TimeoutRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse timeout '%s': %s", c.TimeoutRaw, err.Error())
+	}
+	c.Timeout = timeout
+
+	killAfter, err := parseDuration(c.KillAfterRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse kill after duration '%s': %s", c.KillAfterRaw, err.Error())
+	}
+	c.KillAfter = killAfter
+
+	if c.TimeoutSignalRaw == "" {
+		c.TimeoutSignal = syscall.SIGTERM
+	} else {
+		sig, err := signals.Parse(c.TimeoutSignalRaw)
+		if err != nil {
+			return fmt.Errorf("failed to parse timeout signal '%s': %s", c.TimeoutSignalRaw, err.Error())
+		}
+		c.TimeoutSignal = sig
+	}
+	return nil
+}
+
+// parseDuration parses a non-negative duration, treating an empty string as zero.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return d, nil
 }
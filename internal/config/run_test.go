@@ -0,0 +1,422 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.sophtrust.dev/pkg/zerolog/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// TestRunConfigUnmarshalYAML checks that valid run settings are accepted and invalid ones are rejected.
+func TestRunConfigUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		err   string
+		check func(t *testing.T, cfg *RunConfig)
+	}{
+		{
+			name: "empty",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Timeout != 0 || cfg.KillAfter != 0 || cfg.TimeoutSignal != syscall.SIGTERM {
+					t.Errorf("unexpected timeout settings: %s, %s, %d", cfg.Timeout, cfg.KillAfter, cfg.TimeoutSignal)
+				}
+			},
+		},
+
+		// timeouts
+		{
+			name: "timeout",
+			yaml: "timeout: 5m\nkill_after: 10s\ntimeout_signal: int",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Timeout != 5*time.Minute || cfg.KillAfter != 10*time.Second ||
+					cfg.TimeoutSignal != syscall.SIGINT {
+					t.Errorf("unexpected timeout settings: %s, %s, %d", cfg.Timeout, cfg.KillAfter, cfg.TimeoutSignal)
+				}
+			},
+		},
+		{name: "invalid timeout", yaml: "timeout: soon", err: "failed to parse timeout 'soon'"},
+		{name: "negative timeout", yaml: "timeout: -1s", err: "duration must not be negative"},
+		{name: "invalid kill after", yaml: "kill_after: later", err: "failed to parse kill after duration 'later'"},
+		{name: "unknown timeout signal", yaml: "timeout_signal: NOPE", err: "failed to parse timeout signal 'NOPE'"},
+
+		// sampling
+		{
+			name: "sample interval",
+			yaml: "sample_interval: 250ms",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.SampleInterval != 250*time.Millisecond {
+					t.Errorf("SampleInterval = %s, expected 250ms", cfg.SampleInterval)
+				}
+			},
+		},
+		{
+			name: "invalid sample interval",
+			yaml: "sample_interval: often",
+			err:  "failed to parse sample interval 'often'",
+		},
+		{name: "negative sample interval", yaml: "sample_interval: -1s", err: "duration must not be negative"},
+
+		// forwarded signals
+		{
+			name: "forwarded signals",
+			yaml: "forward_signals: [HUP, int, '']",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if len(cfg.ForwardSignals) != 2 || cfg.ForwardSignals[0] != syscall.SIGHUP {
+					t.Errorf("unexpected forwarded signals: %v", cfg.ForwardSignals)
+				}
+			},
+		},
+		{
+			name: "unknown forwarded signal",
+			yaml: "forward_signals: [NOPE]",
+			err:  "failed to parse forwarded signal 'NOPE'",
+		},
+		{name: "uncatchable forwarded signal", yaml: "forward_signals: [KILL]", err: "signal cannot be caught"},
+
+		// output
+		{
+			name: "output",
+			yaml: "max_output_bytes: 10\nspill_dir: /tmp/spill",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.MaxOutputBytes != 10 || cfg.SpillDir != "/tmp/spill" {
+					t.Errorf("unexpected output settings: %d, %s", cfg.MaxOutputBytes, cfg.SpillDir)
+				}
+			},
+		},
+		{name: "negative max output bytes", yaml: "max_output_bytes: -1", err: "invalid maximum output bytes -1"},
+
+		// extraction rules
+		{
+			name: "extract",
+			yaml: "patterns: {ID: '[a-f0-9]+'}\nextract:\n  - pattern: 'req=%{ID:req} %{INT:n:Int}'\n" +
+				"    types: {Req: STRING}\n    streams: [StdErr]",
+			check: func(t *testing.T, cfg *RunConfig) {
+				rule := cfg.Extract[0]
+				if rule.Regexp == nil || rule.TypeOf("n") != "int" || rule.TypeOf("REQ") != "string" {
+					t.Errorf("unexpected extraction rule: %v, %v", rule.Regexp, rule.Types)
+				}
+				if !rule.AppliesTo(StreamStderr) || rule.AppliesTo(StreamStdout) {
+					t.Errorf("unexpected extraction rule streams: %v", rule.Streams)
+				}
+			},
+		},
+		{name: "extract without pattern", yaml: "extract: [{name: empty}]", err: "extraction rule empty must have"},
+		{name: "extract without fields", yaml: "extract: [{pattern: '\\d+'}]", err: "does not capture any named"},
+		{name: "extract unknown pattern", yaml: "extract: [{pattern: '%{NOPE:x}'}]", err: "unknown pattern: NOPE"},
+		{
+			name: "extract invalid stream",
+			yaml: "extract: [{pattern: '(?P<x>.)', streams: [stdin]}]",
+			err:  "invalid extraction rule #1: invalid stream 'stdin'",
+		},
+		{
+			name: "extract invalid type",
+			yaml: "extract: [{pattern: '(?P<x>.)', types: {x: number}}]",
+			err:  "invalid type 'number' for field 'x' in extraction rule #1",
+		},
+
+		// levels
+		{
+			name: "default levels",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Levels.Stdout != zerolog.InfoLevel || cfg.Levels.Stderr != zerolog.WarnLevel {
+					t.Errorf("unexpected levels: %s, %s", cfg.Levels.Stdout, cfg.Levels.Stderr)
+				}
+				if len(cfg.Levels.Rules) != len(DefaultLevelRules) || cfg.Levels.Rules[0].Regexp == nil {
+					t.Errorf("unexpected level rules: %v", cfg.Levels.Rules)
+				}
+			},
+		},
+		{
+			name: "levels",
+			yaml: "levels:\n  stdout: TRACE\n  stderr: error\n  rules: []",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Levels.Stdout != zerolog.TraceLevel || cfg.Levels.Stderr != zerolog.ErrorLevel {
+					t.Errorf("unexpected levels: %s, %s", cfg.Levels.Stdout, cfg.Levels.Stderr)
+				}
+				if len(cfg.Levels.Rules) != 0 {
+					t.Errorf("unexpected level rules: %v", cfg.Levels.Rules)
+				}
+			},
+		},
+		{name: "invalid stdout level", yaml: "levels: {stdout: loud}", err: "failed to parse stdout level 'loud'"},
+		{name: "invalid stderr level", yaml: "levels: {stderr: disabled}", err: "failed to parse stderr level"},
+		{name: "level rule without pattern", yaml: "levels: {rules: [{level: warn}]}", err: "must have a pattern"},
+		{name: "level rule without level", yaml: "levels: {rules: [{pattern: x}]}", err: "must have a level"},
+		{
+			name: "level rule invalid level",
+			yaml: "levels: {rules: [{pattern: x, level: loud}]}",
+			err:  "failed to parse level 'loud' for level rule #1",
+		},
+		{
+			name: "level rule invalid pattern",
+			yaml: "levels: {rules: [{pattern: '%{NOPE}', level: warn}]}",
+			err:  "failed to compile pattern for level rule #1",
+		},
+		{
+			name: "level rule invalid stream",
+			yaml: "levels: {rules: [{pattern: x, level: warn, streams: [stdin]}]}",
+			err:  "invalid level rule #1: invalid stream 'stdin'",
+		},
+
+		// multiline grouping
+		{
+			name: "multiline preset",
+			yaml: "multiline:\n  preset: Go\n  continuation: '^\\s'\n  max_lines: 50\n  max_wait: 100ms",
+			check: func(t *testing.T, cfg *RunConfig) {
+				m := cfg.Multiline
+				if !m.Enabled() || m.Preset != MultilinePresetGo || m.Continuation != `^\s` || m.MaxLines != 50 ||
+					m.MaxWait != 100*time.Millisecond {
+					t.Errorf("unexpected multiline settings: %+v", m)
+				}
+				if !m.Starts("panic: boom") || !m.Continues("\tmain.go:5", true) || m.Continues("\tmain.go:5", false) {
+					t.Errorf("unexpected multiline patterns: %s, %s", m.StartRegexp, m.ContinuationRegexp)
+				}
+			},
+		},
+		{
+			name: "multiline disabled",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Multiline.Enabled() || cfg.Multiline.Preset != MultilinePresetNone {
+					t.Errorf("unexpected multiline settings: %+v", cfg.Multiline)
+				}
+			},
+		},
+		{name: "invalid multiline preset", yaml: "multiline: {preset: ruby}", err: "invalid multiline preset 'ruby'"},
+		{
+			name: "invalid multiline start",
+			yaml: "multiline: {start: '('}",
+			err:  "failed to compile multiline start pattern",
+		},
+		{
+			name: "invalid multiline continuation",
+			yaml: "multiline: {continuation: '%{NOPE}'}",
+			err:  "failed to compile multiline continuation pattern",
+		},
+		{name: "negative multiline max lines", yaml: "multiline: {max_lines: -1}", err: "invalid multiline maximum"},
+		{name: "invalid multiline max wait", yaml: "multiline: {max_wait: x}", err: "failed to parse multiline"},
+
+		// terminal
+		{
+			name: "pty defaults",
+			yaml: "pty: {enabled: true}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Pty.Cols != DefaultPtyCols || cfg.Pty.Rows != DefaultPtyRows || cfg.ANSI != ANSICollapse {
+					t.Errorf("unexpected terminal settings: %d, %d, %s", cfg.Pty.Cols, cfg.Pty.Rows, cfg.ANSI)
+				}
+			},
+		},
+		{
+			name: "pty",
+			yaml: "pty: {enabled: true, cols: 120, rows: 40}\nansi: STRIP",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Pty.Cols != 120 || cfg.Pty.Rows != 40 || cfg.ANSI != ANSIStrip {
+					t.Errorf("unexpected terminal settings: %d, %d, %s", cfg.Pty.Cols, cfg.Pty.Rows, cfg.ANSI)
+				}
+			},
+		},
+		{
+			name: "ansi without pty",
+			yaml: "ansi: auto",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.ANSI != ANSIRaw {
+					t.Errorf("unexpected ANSI mode: %s", cfg.ANSI)
+				}
+			},
+		},
+		{name: "invalid pty cols", yaml: "pty: {cols: -1}", err: "invalid terminal columns -1"},
+		{name: "invalid pty rows", yaml: "pty: {rows: 65536}", err: "invalid terminal rows 65536"},
+		{name: "invalid ansi mode", yaml: "ansi: color", err: "invalid ANSI mode 'color'"},
+
+		// stdin
+		{
+			name: "stdin default mode",
+			yaml: "stdin: {data: hello, record_content: true}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Stdin.Mode != StdinData || !cfg.Stdin.Record {
+					t.Errorf("unexpected stdin settings: %+v", cfg.Stdin)
+				}
+			},
+		},
+		{
+			name: "stdin file",
+			yaml: "stdin: {file: $JSON_EXEC_TEST_DIR/input.txt}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Stdin.Mode != StdinFile || cfg.Stdin.File != "/tmp/json-exec/input.txt" {
+					t.Errorf("unexpected stdin settings: %+v", cfg.Stdin)
+				}
+			},
+		},
+		{
+			name: "stdin closed",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Stdin.Mode != StdinClose {
+					t.Errorf("unexpected stdin mode: %s", cfg.Stdin.Mode)
+				}
+			},
+		},
+		{name: "stdin file mode without file", yaml: "stdin: {mode: FILE}", err: "a stdin file must be given"},
+		{name: "invalid stdin mode", yaml: "stdin: {mode: pipe}", err: "invalid stdin mode 'pipe'"},
+
+		// environment
+		{
+			name: "env",
+			yaml: "env:\n  files: [$JSON_EXEC_TEST_DIR/.env]\n  record_values: [PATH]\n  vars: [A=1, B]",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Env.Files[0] != "/tmp/json-exec/.env" || !cfg.Env.Record || !cfg.Env.RecordsValue("PATH") {
+					t.Errorf("unexpected environment settings: %+v", cfg.Env)
+				}
+			},
+		},
+		{name: "invalid env pattern", yaml: "env: {deny: ['[']}", err: "invalid environment variable pattern '['"},
+		{name: "invalid env var", yaml: "env: {vars: ['=1']}", err: "invalid environment variable '=1'"},
+
+		// execution context
+		{
+			name: "process defaults",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Process.Cwd != "" || cfg.Process.Umask != -1 || cfg.Process.CPUAffinity != nil {
+					t.Errorf("unexpected process settings: %+v", cfg.Process)
+				}
+			},
+		},
+		{
+			name: "process",
+			yaml: "process:\n  cwd: $JSON_EXEC_TEST_DIR/work\n  umask: '027'\n  nice: 10\n  ionice_class: Idle\n" +
+				"  cpu_affinity: 0-1,3",
+			check: func(t *testing.T, cfg *RunConfig) {
+				p := cfg.Process
+				if cwd, _ := filepath.Abs("/tmp/json-exec/work"); p.Cwd != cwd {
+					t.Errorf("unexpected working directory: %s", p.Cwd)
+				}
+				if p.Umask != 027 || p.Nice != 10 || p.IONiceClass != IONiceIdle || len(p.CPUAffinity) != 3 {
+					t.Errorf("unexpected process settings: %+v", p)
+				}
+			},
+		},
+		{name: "invalid umask", yaml: "process: {umask: '0800'}", err: "invalid umask '0800'"},
+		{name: "umask out of range", yaml: "process: {umask: '1777'}", err: "invalid umask '1777'"},
+		{name: "invalid nice", yaml: "process: {nice: 40}", err: "invalid nice adjustment 40"},
+		{name: "invalid ionice class", yaml: "process: {ionice_class: fast}", err: "invalid I/O scheduling class"},
+		{name: "invalid ionice level", yaml: "process: {ionice_level: 8}", err: "invalid I/O scheduling level 8"},
+		{name: "invalid cpu affinity", yaml: "process: {cpu_affinity: 1-0}", err: "invalid CPU affinity '1-0'"},
+		{
+			name: "user and groups",
+			yaml: "process: {user: nobody, group: '65534', groups: [adm, '4']}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Process.User != "nobody" || cfg.Process.Group != "65534" || len(cfg.Process.Groups) != 2 {
+					t.Errorf("unexpected user and groups: %+v", cfg.Process)
+				}
+			},
+		},
+
+		// resource limits
+		{
+			name: "limits not set",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Limits.Enabled() || cfg.Limits.AddressSpace != -1 || cfg.Limits.Core != -1 {
+					t.Errorf("unexpected limits: %+v", cfg.Limits)
+				}
+			},
+		},
+		{
+			name: "limits",
+			yaml: "limits: {address_space: 1G, core: '0', file_size: 10M, cpu: 1m, open_files: 64, processes: 32}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				l := cfg.Limits
+				if !l.Enabled() || l.AddressSpace != 1<<30 || l.Core != 0 || l.FileSize != 10<<20 ||
+					l.CPU != time.Minute || l.OpenFiles != 64 || l.Processes != 32 {
+					t.Errorf("unexpected limits: %+v", l)
+				}
+			},
+		},
+		{name: "invalid address space limit", yaml: "limits: {address_space: lots}", err: "invalid address space"},
+		{name: "invalid core limit", yaml: "limits: {core: 1X}", err: "invalid core file size limit '1X'"},
+		{name: "invalid file size limit", yaml: "limits: {file_size: '-1'}", err: "invalid file size limit '-1'"},
+		{name: "invalid cpu limit", yaml: "limits: {cpu: forever}", err: "invalid CPU time limit 'forever'"},
+		{name: "cpu limit too small", yaml: "limits: {cpu: 500ms}", err: "value must be at least 1s"},
+		{name: "negative open files limit", yaml: "limits: {open_files: -1}", err: "invalid open files limit -1"},
+		{name: "negative processes limit", yaml: "limits: {processes: -1}", err: "invalid processes limit -1"},
+
+		// cgroup
+		{
+			name: "cgroup disabled",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Cgroup.Enabled || cfg.Cgroup.MemoryMax != -1 || cfg.Cgroup.CPUMax != "" {
+					t.Errorf("unexpected cgroup settings: %+v", cfg.Cgroup)
+				}
+			},
+		},
+		{
+			name: "cgroup",
+			yaml: "cgroup: {memory_max: 256M, cpu_max: '0.5', pids_max: 100, parent: system.slice/jobs}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				c := cfg.Cgroup
+				if !c.Enabled || c.MemoryMax != 256<<20 || c.CPUMax != "50000 100000" || c.PidsMax != 100 ||
+					c.Parent != "/system.slice/jobs" {
+					t.Errorf("unexpected cgroup settings: %+v", c)
+				}
+			},
+		},
+		{name: "invalid cgroup memory limit", yaml: "cgroup: {memory_max: 1.5G}", err: "invalid cgroup memory limit"},
+		{name: "invalid cgroup cpu limit", yaml: "cgroup: {cpu_max: '0'}", err: "invalid cgroup CPU limit '0'"},
+		{name: "negative cgroup process limit", yaml: "cgroup: {pids_max: -1}", err: "invalid cgroup process limit"},
+
+		// seccomp
+		{
+			name: "seccomp defaults",
+			yaml: "{}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				if cfg.Seccomp.Enabled() || cfg.Seccomp.Action != SeccompActionErrno {
+					t.Errorf("unexpected seccomp settings: %+v", cfg.Seccomp)
+				}
+			},
+		},
+		{
+			name: "seccomp",
+			yaml: "seccomp: {action: KILL, allow: [' Read ', ''], deny: [PTRACE], profiles: [No-Network, '']}",
+			check: func(t *testing.T, cfg *RunConfig) {
+				s := cfg.Seccomp
+				if !s.Enabled() || s.Action != SeccompActionKill || !reflect.DeepEqual(s.Allow, []string{"read"}) ||
+					!reflect.DeepEqual(s.Deny, []string{"ptrace"}) ||
+					!reflect.DeepEqual(s.Profiles, []string{SeccompProfileNoNetwork}) {
+					t.Errorf("unexpected seccomp settings: %+v", s)
+				}
+			},
+		},
+		{name: "invalid seccomp action", yaml: "seccomp: {action: trap}", err: "invalid seccomp action 'trap'"},
+		{name: "invalid seccomp profile", yaml: "seccomp: {profiles: [no-fork]}", err: "invalid seccomp profile"},
+	}
+	os.Setenv("JSON_EXEC_TEST_DIR", "/tmp/json-exec")
+	defer os.Unsetenv("JSON_EXEC_TEST_DIR")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg RunConfig
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err.Error())
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error containing '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("expected error containing '%s', got '%s'", tt.err, err.Error())
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, &cfg)
+			}
+		})
+	}
+}
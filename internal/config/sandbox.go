@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxConfig contains the options for isolating the command using namespaces.
+type SandboxConfig struct {
+	// Binds is the list of parsed read-only bind mounts.
+	Binds []SandboxBind `yaml:"-"`
+
+	// Enabled indicates whether or not to run the command in a sandbox.
+	Enabled bool `yaml:"enabled"`
+
+	// Network indicates whether or not the command may use the network of the host.
+	Network bool `yaml:"network"`
+
+	// PrivateTmp indicates whether or not the command is given its own empty /tmp.
+	PrivateTmp bool `yaml:"private_tmp"`
+
+	// ReadOnly is the list of paths made read-only in the sandbox, each either a path or a "$SOURCE:$TARGET" pair
+	// which mounts the source at the target.
+	ReadOnly []string `yaml:"read_only"`
+}
+
+// SandboxBind is a read-only bind mount in the sandbox.
+type SandboxBind struct {
+	// Source is the path on the host.
+	Source string
+
+	// Target is the path in the sandbox.
+	Target string
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *SandboxConfig) validate() error {
+	c.Binds = make([]SandboxBind, 0, len(c.ReadOnly))
+	for _, raw := range c.ReadOnly {
+		if raw == "" {
+			continue
+		}
+		parts := strings.SplitN(os.ExpandEnv(raw), ":", 2)
+		bind := SandboxBind{Source: parts[0], Target: parts[0]}
+		if len(parts) == 2 {
+			bind.Target = parts[1]
+		}
+		if !filepath.IsAbs(bind.Source) || !filepath.IsAbs(bind.Target) {
+			return fmt.Errorf("invalid read-only path '%s': paths must be absolute", raw)
+		}
+		bind.Source = filepath.Clean(bind.Source)
+		bind.Target = filepath.Clean(bind.Target)
+		c.Binds = append(c.Binds, bind)
+	}
+	return nil
+}
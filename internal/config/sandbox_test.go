@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestSandboxConfigValidate checks that read-only paths are parsed into bind mounts with absolute, clean paths.
+//
+// Sources are not checked here since they must exist on the system the command runs on.
+func TestSandboxConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		err   string
+		binds []SandboxBind
+	}{
+		{name: "empty", yaml: "{}", binds: []SandboxBind{}},
+		{name: "paths", yaml: "{read_only: [/opt/tools/, '', '/data/../srv:/mnt/srv/', '$JSON_EXEC_TEST_SANDBOX']}",
+			binds: []SandboxBind{
+				{Source: "/opt/tools", Target: "/opt/tools"},
+				{Source: "/srv", Target: "/mnt/srv"},
+				{Source: "/nonexistent/json-exec", Target: "/nonexistent/json-exec"},
+			}},
+		{name: "relative source", yaml: "{read_only: [opt/tools]}", err: "invalid read-only path 'opt/tools'"},
+		{name: "relative target", yaml: "{read_only: ['/opt:mnt']}", err: "paths must be absolute"},
+	}
+	os.Setenv("JSON_EXEC_TEST_SANDBOX", "/nonexistent/json-exec")
+	defer os.Unsetenv("JSON_EXEC_TEST_SANDBOX")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg RunConfig
+			err := yaml.Unmarshal([]byte("sandbox: "+tt.yaml), &cfg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %s", err.Error())
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error containing '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("expected error containing '%s', got '%s'", tt.err, err.Error())
+			case tt.err != "":
+				return
+			}
+			if !reflect.DeepEqual(cfg.Sandbox.Binds, tt.binds) {
+				t.Errorf("Binds = %+v, expected %+v", cfg.Sandbox.Binds, tt.binds)
+			}
+		})
+	}
+}
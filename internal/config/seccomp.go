@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions taken when the command makes a system call which is not allowed by the seccomp filter.
+const (
+	// SeccompActionErrno fails the system call with EPERM.
+	SeccompActionErrno = "errno"
+
+	// SeccompActionKill kills the command with SIGSYS.
+	SeccompActionKill = "kill"
+)
+
+// Built-in seccomp profiles.
+const (
+	// SeccompProfileNoNetwork denies creating any socket other than a Unix domain socket.
+	SeccompProfileNoNetwork = "no-network"
+
+	// SeccompProfileNoPtrace denies tracing other processes and accessing their memory.
+	SeccompProfileNoPtrace = "no-ptrace"
+)
+
+// SeccompConfig contains the options for filtering the system calls the command may make using seccomp.
+type SeccompConfig struct {
+	// Action is the action taken when a system call which is not allowed is made.
+	Action string `yaml:"action"`
+
+	// Allow is the list of names of the only system calls the command may make, if any.
+	Allow []string `yaml:"allow"`
+
+	// Deny is the list of names of system calls the command may not make.
+	Deny []string `yaml:"deny"`
+
+	// Profiles is the list of built-in profiles whose system calls the command may not make.
+	Profiles []string `yaml:"profiles"`
+}
+
+// Enabled returns whether or not the system calls of the command are filtered.
+func (c *SeccompConfig) Enabled() bool {
+	return len(c.Allow) > 0 || len(c.Deny) > 0 || len(c.Profiles) > 0
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *SeccompConfig) validate() error {
+	c.Action = strings.ToLower(c.Action)
+	switch c.Action {
+	case "":
+		c.Action = SeccompActionErrno
+	case SeccompActionErrno, SeccompActionKill:
+	default:
+		return fmt.Errorf("invalid seccomp action '%s': must be one of: errno or kill", c.Action)
+	}
+
+	c.Allow = parseSyscallNames(c.Allow)
+	c.Deny = parseSyscallNames(c.Deny)
+
+	profiles := make([]string, 0, len(c.Profiles))
+	for _, raw := range c.Profiles {
+		profile := strings.ToLower(strings.TrimSpace(raw))
+		switch profile {
+		case "":
+			continue
+		case SeccompProfileNoNetwork, SeccompProfileNoPtrace:
+			profiles = append(profiles, profile)
+		default:
+			return fmt.Errorf("invalid seccomp profile '%s': must be one of: no-network or no-ptrace", raw)
+		}
+	}
+	c.Profiles = profiles
+	return nil
+}
+
+// parseSyscallNames normalizes the given list of system call names, skipping empty entries.
+func parseSyscallNames(raw []string) []string {
+	names := make([]string, 0, len(raw))
+	for _, name := range raw {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
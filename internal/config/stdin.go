@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Modes for providing stdin to the command.
+const (
+	// StdinClose gives the command an empty stdin.
+	StdinClose = "close"
+
+	// StdinData feeds the command a literal string.
+	StdinData = "data"
+
+	// StdinFile feeds the command the contents of a file.
+	StdinFile = "file"
+
+	// StdinForward forwards the stdin of json-exec to the command.
+	StdinForward = "forward"
+)
+
+// StdinConfig contains the options for providing stdin to the command.
+type StdinConfig struct {
+	// Data is the literal string fed to the command in "data" mode.
+	Data string `yaml:"data"`
+
+	// File is the path to the file fed to the command in "file" mode.
+	File string `yaml:"file"`
+
+	// Mode determines how stdin is provided to the command.
+	//
+	// If empty, the mode is "file" if a file is given, "data" if data is given and "close" otherwise.
+	Mode string `yaml:"mode"`
+
+	// Record indicates whether or not the number of bytes and SHA-256 checksum of stdin are recorded.
+	Record bool `yaml:"record"`
+
+	// RecordContent indicates whether or not the content of stdin is recorded as well.
+	RecordContent bool `yaml:"record_content"`
+}
+
+// validate validates the options, setting default values where necessary.
+func (c *StdinConfig) validate() error {
+	if c.File != "" {
+		c.File = os.ExpandEnv(c.File)
+	}
+	c.Mode = strings.ToLower(c.Mode)
+	switch c.Mode {
+	case "":
+		switch {
+		case c.File != "":
+			c.Mode = StdinFile
+		case c.Data != "":
+			c.Mode = StdinData
+		default:
+			c.Mode = StdinClose
+		}
+	case StdinFile:
+		if c.File == "" {
+			return fmt.Errorf("a stdin file must be given when the stdin mode is 'file'")
+		}
+	case StdinClose, StdinData, StdinForward:
+	default:
+		return fmt.Errorf("invalid stdin mode '%s': must be one of: close, data, file or forward", c.Mode)
+	}
+	if c.RecordContent {
+		c.Record = true
+	}
+	return nil
+}
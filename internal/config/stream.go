@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Output streams of the command.
+const (
+	// StreamStderr is the standard error stream of the command.
+	StreamStderr = "stderr"
+
+	// StreamStdout is the standard output stream of the command.
+	StreamStdout = "stdout"
+)
+
+// normalizeStreams converts the names of the streams to lowercase and ensures that they are valid.
+func normalizeStreams(streams []string) error {
+	for i, s := range streams {
+		streams[i] = strings.ToLower(s)
+		switch streams[i] {
+		case StreamStderr, StreamStdout:
+		default:
+			return fmt.Errorf("invalid stream '%s': must be one of: stdout or stderr", s)
+		}
+	}
+	return nil
+}
+
+// streamsInclude returns whether or not the stream is in the list of streams.
+//
+// An empty list includes every stream.
+func streamsInclude(streams []string, stream string) bool {
+	if len(streams) == 0 {
+		return true
+	}
+	for _, s := range streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
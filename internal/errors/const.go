@@ -10,4 +10,13 @@ const (
 	// Configuration error codes.
 	ConfigLoadFailure  = 40
 	ConfigParseFailure = 41
+
+	// Command error codes.
+	CommandTimedOut      = 124
+	CommandDenied        = 125
+	CommandCannotExecute = 126
+	CommandNotFound      = 127
+
+	// SignalBase is added to the signal number to form the exit code of a command terminated by a signal.
+	SignalBase = 128
 )
@@ -0,0 +1,6 @@
+// Package grok is used for compiling grok-style patterns into regular expressions.
+//
+// A grok pattern is a regular expression which may reference named patterns from a library using the syntax
+// %{NAME}, %{NAME:field} or %{NAME:field:type}. A reference with a field name is converted into a named capture
+// group and the optional type indicates how the captured value should be converted.
+package grok
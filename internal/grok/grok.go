@@ -0,0 +1,109 @@
+package grok
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Types to which captured values may be converted.
+const (
+	TypeBool     = "bool"
+	TypeDuration = "duration"
+	TypeFloat    = "float"
+	TypeInt      = "int"
+	TypeString   = "string"
+)
+
+// maxDepth is the maximum depth of nested pattern references, which guards against recursive patterns.
+const maxDepth = 16
+
+// _reference matches a reference to a named pattern.
+var _reference = regexp.MustCompile(`%\{(\w+)(?::(\w+))?(?::(\w+))?\}`)
+
+// Pattern is a compiled grok pattern.
+type Pattern struct {
+	// Regexp is the compiled regular expression.
+	Regexp *regexp.Regexp
+
+	// Types maps the name of each captured field to the type it should be converted to, if one was given.
+	Types map[string]string
+}
+
+// Compile expands any references to named patterns and compiles the result into a regular expression.
+//
+// Named patterns are looked up first in the custom patterns and then in the built-in library. Pattern names are
+// not case-sensitive.
+func Compile(pattern string, custom map[string]string) (*Pattern, error) {
+	library := make(map[string]string, len(_patterns)+len(custom))
+	for name, p := range _patterns {
+		library[name] = p
+	}
+	for name, p := range custom {
+		library[strings.ToUpper(name)] = p
+	}
+
+	types := map[string]string{}
+	expanded, err := expand(pattern, library, types, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{
+		Regexp: re,
+		Types:  types,
+	}, nil
+}
+
+// IsValidType returns whether or not the string is a supported type for converting captured values.
+func IsValidType(t string) bool {
+	switch t {
+	case TypeBool, TypeDuration, TypeFloat, TypeInt, TypeString:
+		return true
+	}
+	return false
+}
+
+// expand replaces references to named patterns with the patterns themselves.
+//
+// Only references in the top-level pattern may capture fields; fields in nested patterns are ignored.
+func expand(pattern string, library map[string]string, types map[string]string, depth int) (string, error) {
+	if depth > maxDepth {
+		return "", fmt.Errorf("patterns are nested too deeply (possible recursion)")
+	}
+	var err error
+	expanded := _reference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := _reference.FindStringSubmatch(ref)
+		name, field, fieldType := strings.ToUpper(m[1]), m[2], strings.ToLower(m[3])
+		p, ok := library[name]
+		if !ok {
+			err = fmt.Errorf("unknown pattern: %s", m[1])
+			return ""
+		}
+		var sub string
+		if sub, err = expand(p, library, nil, depth+1); err != nil {
+			return ""
+		}
+		if field == "" || types == nil {
+			return "(?:" + sub + ")"
+		}
+		if fieldType != "" {
+			if !IsValidType(fieldType) {
+				err = fmt.Errorf("invalid type '%s' for field '%s'", m[3], field)
+				return ""
+			}
+			types[field] = fieldType
+		}
+		return "(?P<" + field + ">" + sub + ")"
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
@@ -0,0 +1,115 @@
+package grok
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCompile checks that references to named patterns are expanded into capture groups.
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		custom  map[string]string
+		text    string
+		want    map[string]string
+		types   map[string]string
+	}{
+		{name: "plain regexp", pattern: `took (?P<ms>\d+)ms`, text: "took 15ms", want: map[string]string{"ms": "15"},
+			types: map[string]string{}},
+		{name: "named fields", pattern: `%{IP:client} %{HTTPMETHOD:method} %{URIPATHPARAM:path}`,
+			text: "10.0.0.1 GET /index.html?a=1", want: map[string]string{"client": "10.0.0.1", "method": "GET",
+				"path": "/index.html?a=1"}, types: map[string]string{}},
+		{name: "types", pattern: `%{HTTPSTATUS:status:INT} in %{DURATION:elapsed:duration}`, text: "404 in 1.5ms",
+			want:  map[string]string{"status": "404", "elapsed": "1.5ms"},
+			types: map[string]string{"status": TypeInt, "elapsed": TypeDuration}},
+		{name: "no field", pattern: `^%{LOGLEVEL} %{GREEDYDATA:msg}`, text: "WARN disk low",
+			want: map[string]string{"msg": "disk low"}, types: map[string]string{}},
+		{name: "nested patterns", pattern: `%{TIMESTAMP_ISO8601:ts}`, text: "at 2024-01-02T03:04:05Z",
+			want: map[string]string{"ts": "2024-01-02T03:04:05Z"}, types: map[string]string{}},
+		{name: "custom pattern", pattern: `%{ticket:id}`, custom: map[string]string{"Ticket": `[A-Z]+-%{POSINT}`},
+			text: "fixes ABC-123", want: map[string]string{"id": "ABC-123"}, types: map[string]string{}},
+		{name: "custom overrides built-in", pattern: `%{word:w}`, custom: map[string]string{"WORD": `x+`},
+			text: "abc xx", want: map[string]string{"w": "xx"}, types: map[string]string{}},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.pattern, tt.custom)
+		if err != nil {
+			t.Errorf("%s: Compile(%q) failed: %s", tt.name, tt.pattern, err.Error())
+			continue
+		}
+		match := p.Regexp.FindStringSubmatch(tt.text)
+		if match == nil {
+			t.Errorf("%s: pattern %q did not match %q", tt.name, tt.pattern, tt.text)
+			continue
+		}
+		got := map[string]string{}
+		for i, field := range p.Regexp.SubexpNames() {
+			if field != "" {
+				got[field] = match[i]
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: captured %v, expected %v", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(p.Types, tt.types) {
+			t.Errorf("%s: Types = %v, expected %v", tt.name, p.Types, tt.types)
+		}
+	}
+}
+
+// TestCompileErrors checks that invalid patterns are rejected.
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		custom  map[string]string
+		err     string
+	}{
+		{name: "unknown pattern", pattern: `%{NOPE:x}`, err: "unknown pattern: NOPE"},
+		{name: "invalid type", pattern: `%{INT:n:number}`, err: "invalid type 'number' for field 'n'"},
+		{name: "recursion", pattern: `%{A}`, custom: map[string]string{"A": `%{B}`, "B": `%{A}`},
+			err: "nested too deeply"},
+		{name: "invalid regexp", pattern: `(%{INT}`, err: "missing closing )"},
+		{name: "invalid custom pattern", pattern: `%{BAD:x}`, custom: map[string]string{"BAD": `[`},
+			err: "missing closing ]"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.pattern, tt.custom)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Compile(%q) = %v, expected error containing '%s'", tt.name, tt.pattern, err, tt.err)
+		}
+	}
+}
+
+// TestBuiltinPatterns checks that every pattern in the library compiles.
+func TestBuiltinPatterns(t *testing.T) {
+	for name := range _patterns {
+		if _, err := Compile("%{"+name+":value}", nil); err != nil {
+			t.Errorf("Compile(%s) failed: %s", name, err.Error())
+		}
+	}
+}
+
+// TestIsValidType checks that only the supported types are valid.
+func TestIsValidType(t *testing.T) {
+	tests := []struct {
+		t    string
+		want bool
+	}{
+		{t: TypeBool, want: true},
+		{t: TypeDuration, want: true},
+		{t: TypeFloat, want: true},
+		{t: TypeInt, want: true},
+		{t: TypeString, want: true},
+		{t: "INT", want: false},
+		{t: "number", want: false},
+		{t: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsValidType(tt.t); got != tt.want {
+			t.Errorf("IsValidType(%q) = %t, expected %t", tt.t, got, tt.want)
+		}
+	}
+}
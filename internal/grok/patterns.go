@@ -0,0 +1,64 @@
+package grok
+
+// _patterns is the library of built-in patterns.
+//
+// Patterns may reference other patterns in the library.
+var _patterns = map[string]string{
+	// general
+	"WORD":         `\b\w+\b`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+
+	// numbers
+	"INT":          `[+-]?\d+`,
+	"BASE10NUM":    `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"NUMBER":       `%{BASE10NUM}`,
+	"BASE16NUM":    `(?:0[xX])?[0-9A-Fa-f]+`,
+	"POSINT":       `\b[1-9]\d*\b`,
+	"NONNEGINT":    `\b\d+\b`,
+	"DURATION":     `(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+`,
+	"PERCENTAGE":   `%{BASE10NUM}%`,
+	"BYTES":        `%{BASE10NUM}\s?(?:[KMGTP]i?)?B`,
+	"HTTPSTATUS":   `\b[1-5]\d{2}\b`,
+	"HTTPMETHOD":   `\b(?:GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH)\b`,
+	"LOGLEVEL":     `(?i:trace|debug|info(?:rmation)?|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|panic|alert|emerg(?:ency)?)`,
+	"EMAILLOCAL":   `[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+(?:\.[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+)*`,
+	"EMAIL":        `%{EMAILLOCAL}@%{HOSTNAME}`,
+	"USERNAME":     `[a-zA-Z0-9._-]+`,
+	"HOSTNAME":     `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"HOSTPORT":     `%{IPORHOST}:%{POSINT}`,
+	"IPORHOST":     `(?:%{IP}|%{HOSTNAME})`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `[A-Za-z][A-Za-z0-9+.\-]*://(?:%{USERNAME}(?::[^@]*)?@)?(?:%{IPORHOST})?(?::%{POSINT})?(?:%{URIPATHPARAM})?`,
+
+	// network addresses
+	"IPV4": `\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`,
+	"IPV6": `(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}|(?:[0-9A-Fa-f]{1,4}:){1,7}:|` +
+		`(?:[0-9A-Fa-f]{1,4}:){1,6}:[0-9A-Fa-f]{1,4}|::(?:[0-9A-Fa-f]{1,4}:){0,6}[0-9A-Fa-f]{1,4}|::`,
+	"IP":  `(?:%{IPV6}|%{IPV4})`,
+	"MAC": `(?:[0-9A-Fa-f]{2}[:-]){5}[0-9A-Fa-f]{2}`,
+
+	// dates and times
+	"YEAR":              `\d{4}`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0?[1-9]|[12]\d|3[01])`,
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"DAY":               `\b(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)\b`,
+	"HOUR":              `(?:[01]?\d|2[0-3])`,
+	"MINUTE":            `[0-5]\d`,
+	"SECOND":            `(?:[0-5]?\d|60)(?:[.,]\d+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"DATE_US":           `%{MONTHNUM}[/-]%{MONTHDAY}[/-]%{YEAR}`,
+	"DATE_EU":           `%{MONTHDAY}[./-]%{MONTHNUM}[./-]%{YEAR}`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"TIMESTAMP":         `(?:%{TIMESTAMP_ISO8601}|%{HTTPDATE}|%{SYSLOGTIMESTAMP})`,
+}
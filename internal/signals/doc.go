@@ -0,0 +1,2 @@
+// Package signals is used for converting between signal names and the signals themselves.
+package signals
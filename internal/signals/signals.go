@@ -0,0 +1,47 @@
+package signals
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// IsCatchable returns whether or not the signal can be caught and handled by a process.
+func IsCatchable(sig syscall.Signal) bool {
+	name := Name(sig)
+	return name != "SIGKILL" && name != "SIGSTOP"
+}
+
+// Name returns the name of the signal (eg: SIGTERM).
+//
+// If the signal is not known, the signal number is returned in the form SIG<number>.
+func Name(sig syscall.Signal) string {
+	for name, s := range _signals {
+		if s == sig {
+			return name
+		}
+	}
+	return fmt.Sprintf("SIG%d", int(sig))
+}
+
+// Parse converts the given string into a signal.
+//
+// The string may be the name of the signal with or without the SIG prefix (eg: SIGTERM or TERM) in any case or
+// the signal number itself.
+func Parse(s string) (syscall.Signal, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(name); err == nil {
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid signal number: %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig, ok := _signals[name]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal: %s", s)
+}
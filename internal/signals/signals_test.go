@@ -0,0 +1,66 @@
+package signals
+
+import (
+	"syscall"
+	"testing"
+)
+
+// TestParse checks that signals are parsed from their names and numbers.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  syscall.Signal
+		err   bool
+	}{
+		{input: "SIGTERM", want: syscall.SIGTERM},
+		{input: "TERM", want: syscall.SIGTERM},
+		{input: " sigint ", want: syscall.SIGINT},
+		{input: "kill", want: syscall.SIGKILL},
+		{input: "9", want: syscall.Signal(9)},
+		{input: "0", err: true},
+		{input: "-1", err: true},
+		{input: "NOPE", err: true},
+		{input: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %d, expected an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %s", tt.input, err.Error())
+		} else if got != tt.want {
+			t.Errorf("Parse(%q) = %d, expected %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestName checks that signals are named with the SIG prefix and unknown signals by their number.
+func TestName(t *testing.T) {
+	tests := []struct {
+		sig  syscall.Signal
+		want string
+	}{
+		{sig: syscall.SIGTERM, want: "SIGTERM"},
+		{sig: syscall.SIGKILL, want: "SIGKILL"},
+		{sig: syscall.Signal(200), want: "SIG200"},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.sig); got != tt.want {
+			t.Errorf("Name(%d) = %s, expected %s", tt.sig, got, tt.want)
+		}
+	}
+}
+
+// TestIsCatchable checks that only SIGKILL and SIGSTOP cannot be caught.
+func TestIsCatchable(t *testing.T) {
+	if IsCatchable(syscall.SIGKILL) {
+		t.Errorf("SIGKILL must not be catchable")
+	}
+	if !IsCatchable(syscall.SIGTERM) || !IsCatchable(syscall.SIGINT) {
+		t.Errorf("SIGTERM and SIGINT must be catchable")
+	}
+}